@@ -3,10 +3,30 @@ package main
 import (
 	"flag"
 	"fmt"
+	"jpkg/downloader"
 	"jpkg/jvm"
 	"jpkg/pkg/config"
 )
 
+// buildPathDependencies rebuilds the path dependencies whose sources
+// changed and reports whether it succeeded.
+func buildPathDependencies(libDir string) bool {
+	if _, err := downloader.BuildPathDependencies(libDir); err != nil {
+		fmt.Println("Failed to build path dependencies:", err)
+		return false
+	}
+	return true
+}
+
+// classpath returns the installed jars of the given scopes.
+func classpath(libDir string, scopes []string) []string {
+	jars, err := downloader.Classpath(libDir, scopes...)
+	if err != nil {
+		fmt.Println("Failed to read installed dependencies:", err)
+	}
+	return jars
+}
+
 func buildJar() {
 	appConfig := config.GetConfig()
 	tomlConfig, err := config.GetTomlConfig()
@@ -15,11 +35,14 @@ func buildJar() {
 		fmt.Println("Error while getting mainClass from toml")
 	}
 
-	if err := jvm.CompileJava(appConfig.SrcDir, appConfig.BinDir, appConfig.PackageDir); err != nil {
+	if !buildPathDependencies(appConfig.PackageDir) {
+		return
+	}
+	if err := jvm.CompileJava(appConfig.SrcDir, appConfig.BinDir, classpath(appConfig.PackageDir, downloader.CompileScopes), tomlConfig.Release); err != nil {
 		fmt.Println("Failed to compile:", err)
 		return
 	}
-	if path, err := jvm.CreateJar(appConfig.BinDir, "app.jar", tomlConfig.MainClass, "lib"); err != nil {
+	if path, err := jvm.CreateJar(appConfig.BinDir, "app.jar", tomlConfig.MainClass, classpath(appConfig.PackageDir, downloader.RuntimeScopes)); err != nil {
 		fmt.Println("Failed to create JAR:", err)
 	} else {
 		fmt.Println("\nBuild Successfully.")
@@ -36,14 +59,17 @@ func buildNative() {
 		fmt.Println("Error while getting mainClass from toml")
 	}
 
-	if err := jvm.CompileJava(appConfig.SrcDir, appConfig.BinDir, appConfig.PackageDir); err != nil {
+	if !buildPathDependencies(appConfig.PackageDir) {
+		return
+	}
+	if err := jvm.CompileJava(appConfig.SrcDir, appConfig.BinDir, classpath(appConfig.PackageDir, downloader.CompileScopes), tomlConfig.Release); err != nil {
 		fmt.Println("Failed to compile:", err)
 		return
 	}
-	if path, err := jvm.CreateJar(appConfig.BinDir, "app.jar", tomlConfig.MainClass, "lib"); err != nil {
+	if path, err := jvm.CreateJar(appConfig.BinDir, "app.jar", tomlConfig.MainClass, classpath(appConfig.PackageDir, downloader.RuntimeScopes)); err != nil {
 		fmt.Println("Failed to create JAR:", err)
 	} else {
-		err := jvm.BuildNative(path, args[1:])
+		err := jvm.BuildNative(path, classpath(appConfig.PackageDir, downloader.RuntimeScopes), args[1:])
 		if err != nil {
 			fmt.Println("Failed to compile native exec: ", err)
 		}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"jpkg/downloader"
+	"jpkg/pkg/config"
+	"os"
+	"slices"
+)
+
+func exportProject() {
+	format, args := takeFlag(flag.Args(), "format")
+	force := slices.Contains(args, "--force")
+
+	appConfig := config.GetConfig()
+	tomlConfig, err := config.GetTomlConfig()
+	if err != nil {
+		fmt.Println("Failed to read amber.toml:", err)
+		return
+	}
+
+	var file string
+	var content []byte
+	var skipped []string
+	switch format {
+	case "", "maven":
+		file = "pom.xml"
+		content, skipped, err = downloader.ExportMaven(tomlConfig, appConfig.SrcDir)
+	case "gradle":
+		file = "build.gradle.kts"
+		content, skipped, err = downloader.ExportGradle(tomlConfig, appConfig.SrcDir)
+	default:
+		fmt.Println("Unknown format. Use --format maven or --format gradle.")
+		return
+	}
+	if err != nil {
+		fmt.Println("Failed to export:", err)
+		return
+	}
+
+	if _, err := os.Stat(file); err == nil && !force {
+		fmt.Printf("%s already exists, pass --force to overwrite it\n", file)
+		return
+	}
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		fmt.Println("Failed to export:", err)
+		return
+	}
+	for _, name := range skipped {
+		fmt.Printf("Warning: %s could not be exported\n", name)
+	}
+	fmt.Println("Saved file:", file)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"jpkg/downloader"
+	"jpkg/pkg/config"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// buildFiles are looked for when jpkg import is not given a file.
+var buildFiles = []string{"pom.xml", "build.gradle.kts", "build.gradle", filepath.Join("gradle", "libs.versions.toml")}
+
+func importProject() {
+	args := flag.Args()
+	force := slices.Contains(args, "--force")
+	args = slices.DeleteFunc(args, func(arg string) bool { return arg == "--force" })
+
+	path := ""
+	if len(args) > 1 {
+		path = args[1]
+	} else {
+		for _, file := range buildFiles {
+			if _, err := os.Stat(file); err == nil {
+				path = file
+				break
+			}
+		}
+	}
+	if path == "" {
+		fmt.Println("Usage: jpkg import [pom.xml|build.gradle|build.gradle.kts|libs.versions.toml] [--force]")
+		return
+	}
+
+	// amber.toml goes next to the build file, whose paths it reuses
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) == "gradle" && strings.HasSuffix(path, ".versions.toml") {
+		dir = filepath.Dir(dir)
+	}
+	target := filepath.Join(dir, "amber.toml")
+	if _, err := os.Stat(target); err == nil && !force {
+		fmt.Printf("%s already exists, pass --force to overwrite it\n", target)
+		return
+	}
+
+	var imported *downloader.Imported
+	var err error
+	switch name := filepath.Base(path); {
+	case name == "pom.xml" || strings.HasSuffix(name, ".pom"):
+		imported, err = downloader.ImportMaven(path)
+	case strings.HasSuffix(name, ".gradle") || strings.HasSuffix(name, ".gradle.kts"):
+		imported, err = downloader.ImportGradle(path)
+	case strings.HasSuffix(name, ".versions.toml"):
+		imported, err = downloader.ImportCatalog(path)
+	default:
+		fmt.Printf("Cannot import %s, use a pom.xml, a Gradle build script or a version catalog\n", path)
+		return
+	}
+	if err != nil {
+		fmt.Println("Failed to import:", err)
+		return
+	}
+
+	if err := config.CreateConfig(target, imported.Config); err != nil {
+		fmt.Println("Failed to write amber.toml:", err)
+		return
+	}
+	if len(imported.Skipped) > 0 {
+		fmt.Println("Not translated:")
+		for _, skipped := range imported.Skipped {
+			fmt.Println("  " + skipped)
+		}
+	}
+	fmt.Printf("Imported %d dependencies from %s\n", len(imported.Config.Dependencies), path)
+	fmt.Println("Saved file:", target)
+}
@@ -6,13 +6,34 @@ import (
 	"jpkg/downloader"
 	"jpkg/pkg/config"
 	"os"
+	"slices"
 	"strings"
-
-	"github.com/BurntSushi/toml"
 )
 
+// takeFlag removes "--name value" or "--name=value" from args and returns
+// the value together with the remaining arguments.
+func takeFlag(args []string, name string) (string, []string) {
+	var rest []string
+	value := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "--"+name+"=") {
+			value = strings.TrimPrefix(arg, "--"+name+"=")
+		} else if arg == "--"+name && i+1 < len(args) {
+			value = args[i+1]
+			i++
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	return value, rest
+}
+
 func installPackage() {
-	args := flag.Args()
+	scope, args := takeFlag(flag.Args(), "scope")
+	frozen := slices.Contains(args, "--frozen")
+	build := slices.Contains(args, "--build")
+	args = slices.DeleteFunc(args, func(arg string) bool { return arg == "--frozen" || arg == "--build" })
 
 	appConfig := config.GetConfig()
 
@@ -22,37 +43,31 @@ func installPackage() {
 			return
 		}
 
-		var config map[string]map[string]map[string]string
-		if _, err := toml.DecodeFile("amber.toml", &config); err != nil {
-			fmt.Println("Failed to load config:", err)
+		if frozen {
+			if err := downloader.InstallFrozen(appConfig.PackageDir); err != nil {
+				fmt.Println("Frozen install failed:", err)
+				os.Exit(1)
+			}
 			return
 		}
 
-		for dep, info := range config["dependencies"] {
-			origin := info["origin"]
-			version := info["version"]
-
-			if origin == "maven" {
-				url := fmt.Sprintf("pkg:maven/%s@%s", dep, version)
-				if err := downloader.HandleMavenURL(url, appConfig.PackageDir); err != nil {
-					fmt.Println("Failed to install from Maven:", err)
-				}
-			} else if origin == "github" {
-				url := fmt.Sprintf("https://github.com/%s", dep)
-				if err := downloader.HandleGitHubURL(url, appConfig.PackageDir); err != nil {
-					fmt.Println("Failed to install from GitHub:", err)
-				}
-			}
+		if err := downloader.Install(appConfig.PackageDir); err != nil {
+			fmt.Println("Failed to install dependencies:", err)
 		}
 		return
 	}
+	if frozen {
+		fmt.Println("--frozen installs the lock file as is and cannot add dependencies")
+		return
+	}
+
 	url := args[1]
 	if strings.HasPrefix(url, "pkg:maven") {
-		if err := downloader.HandleMavenURL(url, appConfig.PackageDir); err != nil {
+		if err := downloader.HandleMavenURL(url, appConfig.PackageDir, scope); err != nil {
 			fmt.Println("Failed to install from Maven:", err)
 		}
 	} else if strings.HasPrefix(url, "https://github.com") {
-		if err := downloader.HandleGitHubURL(url, appConfig.PackageDir); err != nil {
+		if err := downloader.HandleGitHubURL(url, appConfig.PackageDir, scope, build); err != nil {
 			fmt.Println("Failed to install from GitHub:", err)
 		}
 	} else {
@@ -4,16 +4,34 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"jpkg/downloader"
 	"jpkg/pkg/config"
+	"slices"
 )
 
+const usage = "Usage: jpkg [--offline] <init|import|build|build-native|run|install|remove|update|outdated|why|tree|publish|export>"
+
+func isOfflineFlag(arg string) bool {
+	return arg == "--offline" || arg == "-offline"
+}
+
 func main() {
+	offline := flag.Bool("offline", false, "use only the lock file, lib/ and the artifact store")
 	flag.Parse()
 	args := flag.Args()
 	if len(args) == 0 {
-		fmt.Println("Usage: jpkg [build|run|init]")
+		fmt.Println(usage)
 		return
 	}
+	// --offline may also follow the command, as in jpkg install --offline
+	if rest := slices.DeleteFunc(slices.Clone(args), isOfflineFlag); len(rest) < len(args) {
+		*offline = true
+		flag.CommandLine.Parse(rest)
+		args = flag.Args()
+	}
+	if *offline {
+		downloader.SetOffline()
+	}
 
 	if args[0] == "init" {
 		if err := config.CreateInitialFiles(); err != nil {
@@ -22,12 +40,18 @@ func main() {
 		return
 	}
 
-	_, error := config.GetTomlConfig()
+	if args[0] == "import" {
+		importProject()
+		return
+	}
+
+	tomlConfig, error := config.GetTomlConfig()
 	if error != nil {
 		err := errors.New("initialize the project. then try running [jpkg run|jpkg build]")
 		fmt.Println(err)
 		return
 	}
+	config.GetConfig().SrcDir = tomlConfig.SourceDir()
 
 	switch args[0] {
 	case "build":
@@ -38,7 +62,21 @@ func main() {
 		runApp()
 	case "install":
 		installPackage()
+	case "remove":
+		removePackage()
+	case "why":
+		explainDependency()
+	case "tree":
+		printTree()
+	case "outdated":
+		listOutdated()
+	case "update":
+		updatePackages()
+	case "publish":
+		publishPackage()
+	case "export":
+		exportProject()
 	default:
-		fmt.Println("Invalid command. Use 'build', 'run', or 'install'.")
+		fmt.Println("Invalid command.", usage)
 	}
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"jpkg/downloader"
+	"os"
+	"text/tabwriter"
+)
+
+func orDash(version string) string {
+	if version == "" {
+		return "-"
+	}
+	return version
+}
+
+func listOutdated() {
+	updates, err := downloader.Outdated()
+	if err != nil {
+		fmt.Println("Failed to check for updates:", err)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Dependency\tCurrent\tPatch\tMinor\tLatest")
+	outdated := 0
+	var failed []downloader.Update
+	for _, update := range updates {
+		if update.Err != nil {
+			failed = append(failed, update)
+			continue
+		}
+		if update.Patch == "" && update.Minor == "" && update.Latest == "" {
+			continue
+		}
+		outdated++
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", update.Name, orDash(update.Current), orDash(update.Patch), orDash(update.Minor), orDash(update.Latest))
+	}
+
+	if outdated == 0 && len(failed) == 0 {
+		fmt.Println("All dependencies are up to date.")
+		return
+	}
+	if outdated > 0 {
+		w.Flush()
+	}
+	for _, update := range failed {
+		fmt.Printf("Failed to check %s for updates: %v\n", update.Name, update.Err)
+	}
+}
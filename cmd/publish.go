@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"jpkg/downloader"
+	"jpkg/jvm"
+	"jpkg/pkg/config"
+)
+
+func publishPackage() {
+	target, _ := takeFlag(flag.Args(), "repository")
+
+	appConfig := config.GetConfig()
+	tomlConfig, err := config.GetTomlConfig()
+	if err != nil {
+		fmt.Println("Failed to read amber.toml:", err)
+		return
+	}
+	_, artifactID, version, err := tomlConfig.Coordinates()
+	if err != nil {
+		fmt.Println("Failed to publish:", err)
+		return
+	}
+
+	if !buildPathDependencies(appConfig.PackageDir) {
+		return
+	}
+	compileClasspath := classpath(appConfig.PackageDir, downloader.CompileScopes)
+	if err := jvm.CompileJava(appConfig.SrcDir, appConfig.BinDir, compileClasspath, tomlConfig.Release); err != nil {
+		fmt.Println("Failed to compile:", err)
+		return
+	}
+
+	// The published jar has no Class-Path, its POM lists the dependencies
+	base := fmt.Sprintf("%s-%s", artifactID, version)
+	var publication downloader.Publication
+	if publication.Jar, err = jvm.CreateJar(appConfig.BinDir, base+".jar", tomlConfig.MainClass, nil); err != nil {
+		fmt.Println("Failed to create JAR:", err)
+		return
+	}
+	if publication.Sources, err = jvm.CreateSourcesJar(appConfig.SrcDir, base+"-sources.jar"); err != nil {
+		fmt.Println("Failed to create sources JAR:", err)
+		return
+	}
+	if publication.Javadoc, err = jvm.CreateJavadocJar(appConfig.SrcDir, base+"-javadoc.jar", compileClasspath); err != nil {
+		fmt.Println("Failed to create javadoc JAR:", err)
+		return
+	}
+
+	if err := downloader.Publish(tomlConfig, target, publication); err != nil {
+		fmt.Println("Failed to publish:", err)
+		return
+	}
+	fmt.Printf("\nPublished %s:%s:%s\n", tomlConfig.Group, artifactID, version)
+}
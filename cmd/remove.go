@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"jpkg/downloader"
+	"jpkg/pkg/config"
+)
+
+func removePackage() {
+	args := flag.Args()
+	if len(args) < 2 {
+		fmt.Println("Usage: jpkg remove <name>")
+		return
+	}
+
+	appConfig := config.GetConfig()
+	if err := downloader.Remove(args[1], appConfig.PackageDir); err != nil {
+		fmt.Println("Failed to remove dependency:", err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"jpkg/downloader"
 	"jpkg/jvm"
 	"jpkg/pkg/cache"
 	"jpkg/pkg/config"
@@ -13,10 +14,14 @@ import (
 	"time"
 )
 
-func watchForChanges(srcDir, binDir, libDir, cacheDir, mainClass string, javaCmd *exec.Cmd) {
+func watchForChanges(srcDir, binDir, libDir, cacheDir, mainClass, release string, javaCmd *exec.Cmd) {
 	for {
 		isUptoDate, err := cache.IsCacheUpToDate(srcDir, cacheDir)
-		if err == nil && !isUptoDate {
+		rebuilt, buildErr := downloader.BuildPathDependencies(libDir)
+		if buildErr != nil {
+			fmt.Println("\033[2;37mFailed to build path dependencies:", buildErr, "\033[0m")
+		}
+		if err == nil && (!isUptoDate || rebuilt) {
 			fmt.Println("\033[2;37mFile changes found. Reloading the app...\033[0m")
 
 			// Stop the running process
@@ -37,12 +42,12 @@ func watchForChanges(srcDir, binDir, libDir, cacheDir, mainClass string, javaCmd
 
 			// Recompile and rerun the Java program
 			cache.CopySrcToCache(srcDir, cacheDir)
-			if err := jvm.CompileJava(srcDir, binDir, libDir); err != nil {
+			if err := jvm.CompileJava(srcDir, binDir, classpath(libDir, downloader.CompileScopes), release); err != nil {
 				fmt.Println("\033[2;37mFailed to compile:", err, "\033[0m")
 				return
 			}
 
-			javaCmd = jvm.RunJava(mainClass, binDir, libDir)
+			javaCmd = jvm.RunJava(mainClass, binDir, classpath(libDir, downloader.RuntimeScopes))
 
 			go javaCmd.Run()
 			fmt.Print("\033[H\033[2J")
@@ -67,16 +72,25 @@ func runApp() {
 		fmt.Println("Failed to cache files: ", err)
 	}
 
+	rebuilt, err := downloader.BuildPathDependencies(appConfig.PackageDir)
+	if err != nil {
+		fmt.Println("Failed to build path dependencies:", err)
+		return
+	}
+	if rebuilt {
+		isUptoDate = false
+	}
+
 	if len(args) > 1 && strings.HasSuffix(args[1], ".java") {
 		mainClass = args[1]
 	}
 
 	if isUptoDate {
-		javaCmd = jvm.RunJava(mainClass, appConfig.BinDir, appConfig.BinDir)
+		javaCmd = jvm.RunJava(mainClass, appConfig.BinDir, classpath(appConfig.PackageDir, downloader.RuntimeScopes))
 
 		if len(args) > 1 && slices.Contains(args, "--watch") {
 			go javaCmd.Run()
-			watchForChanges(appConfig.SrcDir, appConfig.BinDir, appConfig.PackageDir, appConfig.CacheDir, mainClass, javaCmd)
+			watchForChanges(appConfig.SrcDir, appConfig.BinDir, appConfig.PackageDir, appConfig.CacheDir, mainClass, tomlConfig.Release, javaCmd)
 			return
 		}
 		javaCmd.Run()
@@ -84,12 +98,12 @@ func runApp() {
 	}
 
 	cache.CopySrcToCache(appConfig.SrcDir, appConfig.CacheDir)
-	if err := jvm.CompileJava(appConfig.SrcDir, appConfig.BinDir, appConfig.PackageDir); err != nil {
+	if err := jvm.CompileJava(appConfig.SrcDir, appConfig.BinDir, classpath(appConfig.PackageDir, downloader.CompileScopes), tomlConfig.Release); err != nil {
 		fmt.Println("Failed to compile:", err)
 		return
 	}
 
-	javaCmd = jvm.RunJava(mainClass, appConfig.BinDir, appConfig.PackageDir)
+	javaCmd = jvm.RunJava(mainClass, appConfig.BinDir, classpath(appConfig.PackageDir, downloader.RuntimeScopes))
 
 	if err != nil && !os.IsNotExist(err) {
 		fmt.Println("Failed to run:", err)
@@ -97,7 +111,7 @@ func runApp() {
 
 	if len(args) > 1 && args[1] == "--watch" {
 		go javaCmd.Run()
-		watchForChanges(appConfig.SrcDir, appConfig.BinDir, appConfig.PackageDir, appConfig.CacheDir, mainClass, javaCmd)
+		watchForChanges(appConfig.SrcDir, appConfig.BinDir, appConfig.PackageDir, appConfig.CacheDir, mainClass, tomlConfig.Release, javaCmd)
 		return
 	}
 	javaCmd.Run()
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"jpkg/downloader"
+	"jpkg/pkg/lock"
+	"os"
+	"strings"
+)
+
+func printTree() {
+	format, args := takeFlag(flag.Args(), "format")
+	if len(args) > 1 {
+		fmt.Println("Usage: jpkg tree [--format text|dot|mermaid|json]")
+		return
+	}
+
+	graph, ok := projectGraph()
+	if !ok {
+		return
+	}
+
+	switch format {
+	case "", "text":
+		printTextTree(graph)
+	case "dot":
+		printDot(graph)
+	case "mermaid":
+		printMermaid(graph)
+	case "json":
+		if err := printJSON(graph); err != nil {
+			fmt.Println("Failed to write graph:", err)
+		}
+	default:
+		fmt.Printf("Unknown format %q, use text, dot, mermaid or json\n", format)
+	}
+}
+
+// projectGraph loads the dependency graph for tree and why, warning on
+// stderr when the lock file it comes from no longer matches amber.toml.
+func projectGraph() (*downloader.Graph, bool) {
+	graph, drift, err := downloader.ProjectGraph()
+	if err != nil {
+		fmt.Println("Failed to load dependencies:", err)
+		return nil, false
+	}
+	if len(drift) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %s does not match amber.toml, run jpkg install to update it:\n  %s\n", lock.FileName, strings.Join(drift, "\n  "))
+	}
+	return graph, true
+}
+
+// edgeNote describes how the version an edge asked for relates to the
+// version that was selected, empty when they agree.
+func edgeNote(requested downloader.Artifact, node *downloader.Node) string {
+	switch {
+	case requested.Version == node.Version:
+		return ""
+	case node.Overridden:
+		return fmt.Sprintf("%s overridden to %s", requested.Version, node.Version)
+	default:
+		return fmt.Sprintf("omitted for conflict with %s", node.Version)
+	}
+}
+
+// nodeLabel describes a selected artifact, followed by notes on how it
+// was reached.
+func nodeLabel(node *downloader.Node, notes ...string) string {
+	if node.Overridden && len(notes) == 0 {
+		notes = append(notes, "forced by [overrides]")
+	}
+	if node.BuiltFromSource() {
+		notes = append(notes, "built from source")
+	}
+	label := fmt.Sprintf("%s [%s]", node.Artifact, node.Scope)
+	if len(notes) > 0 {
+		label += " (" + strings.Join(notes, "; ") + ")"
+	}
+	return label
+}
+
+// printTextTree prints the graph the way mvn dependency:tree does. Each
+// artifact is expanded once, where it is nearest to a root.
+func printTextTree(graph *downloader.Graph) {
+	expanded := map[string]bool{}
+
+	var walk func(node *downloader.Node, prefix string)
+	walk = func(node *downloader.Node, prefix string) {
+		expanded[node.Key()] = true
+
+		type line struct {
+			text  string
+			child *downloader.Node
+		}
+		var lines []line
+		for _, dep := range node.Dependencies {
+			child, ok := graph.Selected(dep.Key())
+			if !ok {
+				lines = append(lines, line{text: dep.String() + " (failed to resolve)"})
+				continue
+			}
+			note := edgeNote(dep, child)
+			if note != "" && !child.Overridden {
+				lines = append(lines, line{text: fmt.Sprintf("%s (%s)", dep, note)})
+				continue
+			}
+			var notes []string
+			if note != "" {
+				notes = append(notes, note)
+			}
+			if expanded[child.Key()] || child.Depth != node.Depth+1 {
+				notes = append(notes, "omitted for duplicate")
+				lines = append(lines, line{text: nodeLabel(child, notes...)})
+				continue
+			}
+			lines = append(lines, line{text: nodeLabel(child, notes...), child: child})
+		}
+		for _, excluded := range graph.Excluded {
+			if excluded.From.Key() == node.Key() {
+				lines = append(lines, line{text: excluded.Artifact.String() + " (excluded)"})
+			}
+		}
+
+		for i, l := range lines {
+			branch, indent := "+- ", "|  "
+			if i == len(lines)-1 {
+				branch, indent = "\\- ", "   "
+			}
+			fmt.Println(prefix + branch + l.text)
+			if l.child != nil {
+				walk(l.child, prefix+indent)
+			}
+		}
+	}
+
+	for _, root := range graph.Roots {
+		node, ok := graph.Selected(root.Key())
+		if !ok || expanded[node.Key()] {
+			continue
+		}
+		fmt.Println(nodeLabel(node))
+		walk(node, "")
+	}
+}
+
+// printDot prints the graph in Graphviz format. Edges that lost a version
+// conflict are dashed and excluded dependencies are drawn in grey.
+func printDot(graph *downloader.Graph) {
+	fmt.Println("digraph dependencies {")
+	fmt.Println("  node [shape=box];")
+	for _, key := range graph.Order {
+		node := graph.Nodes[key]
+		attrs := fmt.Sprintf("label=%q", fmt.Sprintf("%s\n%s [%s]", key, node.Version, node.Scope))
+		if node.Overridden {
+			attrs += ", style=bold"
+		}
+		fmt.Printf("  %q [%s];\n", key, attrs)
+	}
+	for _, key := range graph.Order {
+		for _, dep := range graph.Nodes[key].Dependencies {
+			child, ok := graph.Selected(dep.Key())
+			if !ok {
+				continue
+			}
+			if note := edgeNote(dep, child); note != "" {
+				fmt.Printf("  %q -> %q [style=dashed, label=%q];\n", key, dep.Key(), note)
+			} else {
+				fmt.Printf("  %q -> %q;\n", key, dep.Key())
+			}
+		}
+	}
+	for _, excluded := range graph.Excluded {
+		id := "excluded " + excluded.Key()
+		fmt.Printf("  %q [label=%q, color=grey, fontcolor=grey];\n", id, excluded.Artifact.String()+"\n(excluded)")
+		fmt.Printf("  %q -> %q [style=dotted, color=grey];\n", excluded.From.Key(), id)
+	}
+	fmt.Println("}")
+}
+
+// printMermaid prints the graph as a Mermaid flowchart.
+func printMermaid(graph *downloader.Graph) {
+	ids := map[string]string{}
+	for i, key := range graph.Order {
+		ids[key] = fmt.Sprintf("n%d", i)
+	}
+
+	fmt.Println("graph TD")
+	for _, key := range graph.Order {
+		node := graph.Nodes[key]
+		label := fmt.Sprintf("%s<br/>%s [%s]", key, node.Version, node.Scope)
+		if node.Overridden {
+			label += "<br/>forced by overrides"
+		}
+		fmt.Printf("  %s[\"%s\"]\n", ids[key], mermaidEscape(label))
+	}
+	for _, key := range graph.Order {
+		for _, dep := range graph.Nodes[key].Dependencies {
+			child, ok := graph.Selected(dep.Key())
+			if !ok {
+				continue
+			}
+			if note := edgeNote(dep, child); note != "" {
+				fmt.Printf("  %s -. \"%s\" .-> %s\n", ids[key], mermaidEscape(note), ids[dep.Key()])
+			} else {
+				fmt.Printf("  %s --> %s\n", ids[key], ids[dep.Key()])
+			}
+		}
+	}
+	for i, excluded := range graph.Excluded {
+		id := fmt.Sprintf("x%d", i)
+		fmt.Printf("  %s[\"%s<br/>excluded\"]\n", id, mermaidEscape(excluded.Artifact.String()))
+		fmt.Printf("  %s -.-x %s\n", ids[excluded.From.Key()], id)
+	}
+}
+
+func mermaidEscape(text string) string {
+	return strings.ReplaceAll(text, `"`, "#quot;")
+}
+
+type jsonEdge struct {
+	ID        string `json:"id"`
+	Requested string `json:"requested"`
+	Conflict  bool   `json:"conflict,omitempty"`
+}
+
+type jsonNode struct {
+	ID           string     `json:"id"`
+	GroupID      string     `json:"groupId"`
+	ArtifactID   string     `json:"artifactId"`
+	Version      string     `json:"version"`
+	Classifier   string     `json:"classifier,omitempty"`
+	Scope        string     `json:"scope"`
+	Depth        int        `json:"depth"`
+	Overridden   bool       `json:"overridden,omitempty"`
+	Source       bool       `json:"builtFromSource,omitempty"`
+	Dependencies []jsonEdge `json:"dependencies,omitempty"`
+}
+
+type jsonExcluded struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+	From    string `json:"from"`
+}
+
+// printJSON prints the graph as a flat list of nodes, each with the edges
+// to its dependencies.
+func printJSON(graph *downloader.Graph) error {
+	out := struct {
+		Strategy string         `json:"strategy"`
+		Roots    []string       `json:"roots"`
+		Nodes    []jsonNode     `json:"nodes"`
+		Excluded []jsonExcluded `json:"excluded,omitempty"`
+	}{Strategy: graph.Strategy, Roots: []string{}, Nodes: []jsonNode{}}
+
+	for _, root := range graph.Roots {
+		out.Roots = append(out.Roots, root.Key())
+	}
+	for _, key := range graph.Order {
+		node := graph.Nodes[key]
+		n := jsonNode{
+			ID:         key,
+			GroupID:    node.GroupID,
+			ArtifactID: node.ArtifactID,
+			Version:    node.Version,
+			Classifier: node.Classifier,
+			Scope:      node.Scope,
+			Depth:      node.Depth,
+			Overridden: node.Overridden,
+			Source:     node.BuiltFromSource(),
+		}
+		for _, dep := range node.Dependencies {
+			child, ok := graph.Selected(dep.Key())
+			n.Dependencies = append(n.Dependencies, jsonEdge{
+				ID:        dep.Key(),
+				Requested: dep.Version,
+				Conflict:  ok && child.Version != dep.Version,
+			})
+		}
+		out.Nodes = append(out.Nodes, n)
+	}
+	for _, excluded := range graph.Excluded {
+		out.Excluded = append(out.Excluded, jsonExcluded{ID: excluded.Key(), Version: excluded.Version, From: excluded.From.Key()})
+	}
+
+	content, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(content))
+	return nil
+}
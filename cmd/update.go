@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"jpkg/downloader"
+	"jpkg/pkg/config"
+	"os"
+	"strings"
+)
+
+func updatePackages() {
+	level, args := takeFlag(flag.Args(), "to")
+	if level == "" {
+		level = "latest"
+	}
+	if level != "patch" && level != "minor" && level != "latest" {
+		fmt.Println("Usage: jpkg update [name] [--to patch|minor|latest]")
+		return
+	}
+
+	appConfig := config.GetConfig()
+	tomlConfig, err := config.GetTomlConfig()
+	if err != nil {
+		fmt.Println("Failed to load config:", err)
+		return
+	}
+
+	updates, err := downloader.Outdated()
+	if err != nil {
+		fmt.Println("Failed to check for updates:", err)
+		return
+	}
+
+	// Leave amber.toml as it was when the new versions cannot be installed
+	original, err := os.ReadFile("amber.toml")
+	if err != nil {
+		fmt.Println("Failed to read amber.toml:", err)
+		return
+	}
+	restore := func() {
+		if err := os.WriteFile("amber.toml", original, 0644); err != nil {
+			fmt.Println("Failed to restore amber.toml:", err)
+		}
+	}
+
+	found := len(args) < 2
+	for _, update := range updates {
+		if len(args) > 1 && update.Name != args[1] && !strings.HasSuffix(update.Name, "/"+args[1]) {
+			continue
+		}
+		found = true
+		if update.Err != nil {
+			fmt.Printf("Failed to check %s for updates: %v\n", update.Name, update.Err)
+			continue
+		}
+
+		target := update.Latest
+		switch level {
+		case "patch":
+			target = update.Patch
+		case "minor":
+			target = update.Minor
+		}
+		if target == "" {
+			continue
+		}
+
+		dep := tomlConfig.Dependencies[update.Name]
+		switch update.Origin {
+		case "maven":
+			dep.Version = target
+		case "github":
+			dep.Tag = target
+		default:
+			continue
+		}
+		if err := config.SaveDependency(update.Name, dep); err != nil {
+			fmt.Println("Failed to update amber.toml:", err)
+			restore()
+			return
+		}
+		fmt.Printf("%s: %s -> %s\n", update.Name, update.Current, target)
+	}
+
+	if !found {
+		fmt.Printf("%s is not a dependency of this project\n", args[1])
+		return
+	}
+
+	if err := downloader.Install(appConfig.PackageDir); err != nil {
+		fmt.Println("Failed to install dependencies:", err)
+		restore()
+	}
+}
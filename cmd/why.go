@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"jpkg/downloader"
+	"strings"
+)
+
+func explainDependency() {
+	args := flag.Args()
+	if len(args) < 2 {
+		fmt.Println("Usage: jpkg why <artifact>")
+		return
+	}
+
+	graph, ok := projectGraph()
+	if !ok {
+		return
+	}
+
+	keys := graph.Find(args[1])
+	excluded := graph.FindExcluded(args[1])
+	if len(keys) == 0 && len(excluded) == 0 {
+		fmt.Printf("%s is not a dependency of this project\n", args[1])
+		return
+	}
+
+	for _, key := range keys {
+		node, _ := graph.Selected(key)
+		if node.Overridden {
+			fmt.Printf("%s [%s] (forced by [overrides])\n", node.Artifact, node.Scope)
+		} else {
+			fmt.Printf("%s [%s] (selected by %s strategy)\n", node.Artifact, node.Scope, graph.Strategy)
+		}
+
+		for _, path := range graph.Paths(key) {
+			line := "  " + chain(path)
+			if requested := path[len(path)-1].Version; requested != node.Version && node.Overridden {
+				line += fmt.Sprintf(" (%s overridden to %s)", requested, node.Version)
+			} else if requested != node.Version {
+				line += fmt.Sprintf(" (%s omitted for %s)", requested, node.Version)
+			}
+			fmt.Println(line)
+		}
+	}
+
+	for _, dep := range excluded {
+		fmt.Printf("%s (excluded)\n", dep.Artifact)
+		for _, path := range graph.Paths(dep.From.Key()) {
+			fmt.Printf("  %s -> %s (excluded)\n", chain(path), dep.Artifact)
+		}
+	}
+}
+
+func chain(path []downloader.Artifact) string {
+	var names []string
+	for _, a := range path {
+		names = append(names, a.String())
+	}
+	return strings.Join(names, " -> ")
+}
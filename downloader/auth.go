@@ -0,0 +1,84 @@
+package downloader
+
+import (
+	"fmt"
+	"jpkg/pkg/config"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// repositoryURLs maps the url of every repository in use to its id, so
+// that requests can be matched with the credentials of their repository.
+var repositoryURLs sync.Map
+
+// registerRepositories records the ids credentials are looked up by.
+// Mirrors are other servers than the repositories they replace, so they
+// are registered under an id of their own.
+func registerRepositories(repositories []config.Repository) {
+	for _, repository := range repositories {
+		id := repository.ID
+		if repository.Mirror != "" {
+			id = "mirror:" + repository.Mirror
+		}
+		repositoryURLs.Store(strings.TrimSuffix(repository.URL, "/")+"/", id)
+	}
+}
+
+// repositoryID returns the id of the repository url belongs to, the one
+// with the longest matching url when several do.
+func repositoryID(url string) string {
+	id, longest := "", 0
+	repositoryURLs.Range(func(key, value any) bool {
+		if prefix := key.(string); strings.HasPrefix(url, prefix) && len(prefix) > longest {
+			id, longest = value.(string), len(prefix)
+		}
+		return true
+	})
+	return id
+}
+
+func isGitHubHost(host string) bool {
+	return host == "github.com" || strings.HasSuffix(host, ".github.com")
+}
+
+// authorize adds the credentials configured for the request's repository
+// or host. Requests to GitHub fall back to $GITHUB_TOKEN.
+func authorize(req *http.Request) error {
+	settings, err := userSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	hosts := []string{req.URL.Host}
+	if req.URL.Port() != "" {
+		hosts = append(hosts, req.URL.Hostname())
+	}
+	github := isGitHubHost(req.URL.Hostname())
+	if github {
+		hosts = append(hosts, "github.com")
+	}
+
+	credentials, err := settings.CredentialsFor(repositoryID(req.URL.String()), hosts...)
+	if err != nil {
+		return err
+	}
+	if credentials == nil && github {
+		for _, name := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+			if token := os.Getenv(name); token != "" {
+				credentials = &config.Credentials{Token: token}
+				break
+			}
+		}
+	}
+
+	switch {
+	case credentials == nil:
+	case credentials.Token != "":
+		req.Header.Set("Authorization", "Bearer "+credentials.Token)
+	case credentials.Username != "" || credentials.Password != "":
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+	return nil
+}
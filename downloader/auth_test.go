@@ -0,0 +1,98 @@
+package downloader
+
+import (
+	"jpkg/pkg/config"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// useSettings makes settings.toml hold content for the rest of the test.
+func useSettings(t *testing.T, content string) {
+	t.Helper()
+	home := t.TempDir()
+	if err := os.WriteFile(filepath.Join(home, "settings.toml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AMBER_HOME", home)
+	userSettings = sync.OnceValues(config.GetSettings)
+	t.Cleanup(func() {
+		userSettings = sync.OnceValues(config.GetSettings)
+		repositoryURLs.Range(func(key, _ any) bool {
+			repositoryURLs.Delete(key)
+			return true
+		})
+	})
+}
+
+func TestAuthorizeMirrors(t *testing.T) {
+	useSettings(t, `
+[mirrors]
+central = "https://mirror.example.com/maven2"
+company = "https://company-mirror.example.com/maven"
+
+[credentials.central]
+username = "central-user"
+password = "central-secret"
+
+[credentials.company]
+username = "company-user"
+password = "company-secret"
+
+[credentials."mirror:company"]
+token = "mirror-token"
+
+[credentials.internal]
+username = "internal-user"
+password = "internal-secret"
+
+[credentials."mirror.example.com"]
+username = "mirror-user"
+password = "mirror-secret"
+`)
+
+	cfg := &config.Config{}
+	cfg.AddRepository("company", "https://repo.example.com/maven")
+	cfg.AddRepository("internal", "https://internal.example.com/maven")
+	repositories, err := projectRepositories(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, repository := range repositories {
+		if mirrored := repository.Mirror != ""; mirrored != (repository.ID != "internal") {
+			t.Errorf("%s mirrored = %v", repository.ID, mirrored)
+		}
+	}
+
+	tests := []struct {
+		url      string
+		username string
+		token    string
+	}{
+		// a mirror is matched by its host, never by the repository it replaces
+		{"https://mirror.example.com/maven2/a/b/1/b-1.pom", "mirror-user", ""},
+		// or by "mirror:" and its [mirrors] key
+		{"https://company-mirror.example.com/maven/a/b/1/b-1.pom", "", "mirror-token"},
+		{"https://internal.example.com/maven/a/b/1/b-1.pom", "internal-user", ""},
+		{"https://elsewhere.example.com/a", "", ""},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := authorize(req); err != nil {
+			t.Fatal(err)
+		}
+		username, _, _ := req.BasicAuth()
+		token := ""
+		if auth := req.Header.Get("Authorization"); len(auth) > 7 && auth[:7] == "Bearer " {
+			token = auth[7:]
+		}
+		if username != tt.username || token != tt.token {
+			t.Errorf("%s: user %q, token %q, want user %q, token %q", tt.url, username, token, tt.username, tt.token)
+		}
+	}
+}
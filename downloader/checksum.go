@@ -0,0 +1,123 @@
+package downloader
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"jpkg/pkg/config"
+	"os"
+	"strings"
+)
+
+// Checksum algorithms in order of preference. Maven repositories publish
+// them as sidecar files next to every artifact.
+var checksumAlgorithms = []string{"sha512", "sha256", "sha1"}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha512":
+		return sha512.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+}
+
+func fileChecksum(path, algorithm string) (string, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyFile checks the file against a hash in "algorithm:hex" form.
+func verifyFile(path, expected string) error {
+	algorithm, value, ok := strings.Cut(expected, ":")
+	if !ok {
+		return fmt.Errorf("malformed hash %q", expected)
+	}
+
+	actual, err := fileChecksum(path, algorithm)
+	if err != nil {
+		return err
+	}
+	if actual != strings.ToLower(value) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s:%s", expected, algorithm, actual)
+	}
+	return nil
+}
+
+// fetchChecksum reads the strongest checksum the repository publishes for
+// the given path.
+func fetchChecksum(repository config.Repository, path string) (string, string, error) {
+	for _, algorithm := range checksumAlgorithms {
+		body, _, err := openURL(repository.URL + "/" + path + "." + algorithm)
+		if errors.Is(err, errNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		content, err := io.ReadAll(io.LimitReader(body, 1024))
+		body.Close()
+		if err != nil {
+			return "", "", err
+		}
+
+		// Sidecars hold the hex digest, sometimes followed by the file name
+		fields := strings.Fields(string(content))
+		if len(fields) == 0 {
+			return "", "", fmt.Errorf("empty %s checksum for %s", algorithm, path)
+		}
+		return algorithm, strings.ToLower(fields[0]), nil
+	}
+	return "", "", errNotFound
+}
+
+// verifyDownload checks a freshly downloaded artifact against the checksum
+// published by the repository it came from and returns the verified hash.
+// Artifacts without a published checksum are hashed locally so that later
+// installs can still detect changes.
+func verifyDownload(repository config.Repository, path, dest string) (string, error) {
+	algorithm, expected, err := fetchChecksum(repository, path)
+	if errors.Is(err, errNotFound) {
+		downloads.Printf("Warning: %s publishes no checksum for %s\n", repository.ID, path)
+		actual, err := fileChecksum(dest, "sha256")
+		if err != nil {
+			return "", err
+		}
+		return "sha256:" + actual, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksum for %s: %w", path, err)
+	}
+
+	hash := algorithm + ":" + expected
+	if err := verifyFile(dest, hash); err != nil {
+		return "", fmt.Errorf("%s from %s: %w", path, repository.ID, err)
+	}
+	return hash, nil
+}
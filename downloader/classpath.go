@@ -0,0 +1,55 @@
+package downloader
+
+import (
+	"jpkg/pkg/config"
+	"jpkg/pkg/lock"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	// CompileScopes are the scopes javac needs to see.
+	CompileScopes = []string{config.ScopeCompile, config.ScopeProvided}
+	// RuntimeScopes are the scopes that have to ship with the application.
+	RuntimeScopes = []string{config.ScopeCompile, config.ScopeRuntime}
+)
+
+// Classpath returns the jars in libDir that belong to one of the given
+// scopes. Jars that are not in the lock file are treated as compile scoped.
+func Classpath(libDir string, scopes ...string) ([]string, error) {
+	if _, err := os.Stat(libDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	l, err := lock.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	jarScopes := map[string]string{}
+	for _, a := range l.Artifacts {
+		jarScopes[a.File] = config.Dependency{Scope: a.Scope}.EffectiveScope()
+	}
+
+	var jars []string
+	err = filepath.Walk(libDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".jar") {
+			return nil
+		}
+
+		scope, ok := jarScopes[filepath.Base(path)]
+		if !ok {
+			scope = config.ScopeCompile
+		}
+		if slices.Contains(scopes, scope) {
+			jars = append(jars, path)
+		}
+		return nil
+	})
+	return jars, err
+}
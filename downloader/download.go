@@ -1,17 +1,15 @@
 package downloader
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"jpkg/pkg/config"
-	"net/http"
+	"jpkg/pkg/lock"
+	neturl "net/url"
 	"os"
-	"path/filepath"
 	"strings"
-
-	"github.com/schollz/progressbar/v3"
+	"time"
 )
 
 type Asset struct {
@@ -20,172 +18,261 @@ type Asset struct {
 	DonwloadUrl string `json:"browser_download_url"`
 }
 
-type DependencyLock struct {
-	Dependencies map[string]string `json:"dependencies"`
+type GithubRes struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
 }
 
-type GithubRes struct {
-	Assets []Asset `json:"assets"`
+// partPath is where the download of dest is written until it has been
+// verified.
+func partPath(dest string) string {
+	return dest + ".part"
 }
 
+// downloadFile downloads url to dest. A partial file left at dest by an
+// earlier attempt is continued, and transient failures are retried with
+// backoff.
 func downloadFile(name, url, dest string) error {
-	resp, err := http.Head(url)
+	settings, err := userSettings()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to load settings: %w", err)
 	}
-	defer resp.Body.Close()
-
-	size := resp.ContentLength
-
-	// Create a progress bar
-	bar := progressbar.DefaultBytes(size,
-		name,
-	)
 
-	// Start downloading the file
-	resp, err = http.Get(url)
-	if err != nil {
-		return err
+	for attempt := 0; ; attempt++ {
+		err := fetchFile(name, url, dest)
+		if err == nil || !retryable(err) || attempt >= settings.DownloadRetries() {
+			return err
+		}
+		delay := time.Duration(1<<attempt) * time.Second
+		downloads.Printf("Retrying %s in %s: %v\n", name, delay, err)
+		time.Sleep(delay)
 	}
-	defer resp.Body.Close()
+}
 
-	out, err := os.Create(dest)
+func fetchFile(name, url, dest string) error {
+	var offset int64
+	if info, err := os.Stat(dest); err == nil {
+		offset = info.Size()
+	}
+	body, size, resumed, err := openRange(url, offset)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
-
-	// Create a writer to update the progress bar
-	writer := io.MultiWriter(out, bar)
+	defer body.Close()
 
-	// Copy the content to the file while updating the progress bar
-	_, err = io.Copy(writer, resp.Body)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resumed {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	} else {
+		offset = 0
+	}
+	out, err := os.OpenFile(dest, flags, 0644)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Copy the content to the file while updating the progress view
+	transfer := downloads.start(name, offset, size)
+	_, err = io.Copy(io.MultiWriter(out, transfer), body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	downloads.finish(transfer, err)
+	return err
 }
 
-func writeJSONLockFile(name, jarFileName string) error {
-	lockFile := "dependencies-lock.json"
-	var lock DependencyLock
-
-	// Load existing lock file
-	if _, err := os.Stat(lockFile); err == nil {
-		file, err := os.Open(lockFile)
-		if err != nil {
-			return err
+// downloadArtifact saves the artifact's own file to dest and reports the
+// repository it came from.
+func downloadArtifact(repositories []config.Repository, a Artifact, dest string) (config.Repository, error) {
+	path := a.filePath()
+	var failures []string
+	unreachable := false
+	for _, repository := range repositories {
+		err := downloadFile(a.String(), repository.URL+"/"+path, dest)
+		if err == nil {
+			return repository, nil
 		}
-		defer file.Close()
-		decoder := json.NewDecoder(file)
-		if err := decoder.Decode(&lock); err != nil {
-			return err
+		if errors.Is(err, errOffline) {
+			unreachable = true
+		} else if !errors.Is(err, errNotFound) {
+			failures = append(failures, fmt.Sprintf("%s: %v", repository.ID, err))
 		}
-	} else {
-		lock.Dependencies = make(map[string]string)
-	}
-
-	// Update the lock with new dependency
-	lock.Dependencies[name] = jarFileName
-
-	// Save the updated lock file
-	file, err := os.Create(lockFile)
-	if err != nil {
-		return err
 	}
-	defer file.Close()
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(&lock)
+	return config.Repository{}, fetchError(path, failures, unreachable)
 }
 
-func HandleMavenURL(url string, libDir string) error {
+// parseMavenURL reads a pkg:maven/group/artifact@version purl, which may
+// carry classifier and type qualifiers.
+func parseMavenURL(url string) (Artifact, error) {
 	// Remove the "pkg:maven/" prefix
 	trimmedURL := strings.TrimPrefix(url, "pkg:maven/")
+	trimmedURL, rawQualifiers, _ := strings.Cut(trimmedURL, "?")
+	qualifiers, err := neturl.ParseQuery(rawQualifiers)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("invalid Maven URL qualifiers: %w", err)
+	}
 	parts := strings.Split(trimmedURL, "/")
 	if len(parts) != 2 {
-		return fmt.Errorf("invalid Maven URL format")
+		return Artifact{}, fmt.Errorf("invalid Maven URL format")
 	}
 
-	groupID := strings.ReplaceAll(parts[0], ".", "/")
 	artifactVersionStr := strings.Split(parts[1], "@")
-	artifactID := artifactVersionStr[0]
-	version := artifactVersionStr[1]
-	jarFileName := fmt.Sprintf("%s-%s.jar", artifactID, version)
-	// pomFileName := fmt.Sprintf("%s-%s.jar", artifactID, version)
-	jarDownloadURL := fmt.Sprintf("https://repo1.maven.org/maven2/%s/%s/%s/%s", groupID, artifactID, version, jarFileName)
-	// pomDownloadURL := fmt.Sprintf("https://repo1.maven.org/maven2/%s/%s/%s/%s", groupID, artifactID, version, pomFileName)
-
-	writeJSONLockFile(artifactID, jarFileName)
-
-	// Download the JAR file
-	if _, err := os.Stat(libDir); os.IsNotExist(err) {
-		os.Mkdir(libDir, os.ModePerm)
+	if len(artifactVersionStr) != 2 || parts[0] == "" || artifactVersionStr[0] == "" || artifactVersionStr[1] == "" {
+		return Artifact{}, fmt.Errorf("invalid Maven URL format")
 	}
-	dest := filepath.Join(libDir, jarFileName)
+	return Artifact{
+		GroupID:    parts[0],
+		ArtifactID: artifactVersionStr[0],
+		Version:    artifactVersionStr[1],
+		Classifier: qualifiers.Get("classifier"),
+		Type:       qualifiers.Get("type"),
+	}, nil
+}
 
-	if err := config.SaveDependency(fmt.Sprintf("%s/%s", parts[0], artifactID), "maven", version); err != nil {
+// HandleMavenURL installs a maven dependency. An empty scope keeps the
+// scope already declared in amber.toml, if any.
+func HandleMavenURL(url, libDir, scope string) error {
+	root, err := parseMavenURL(url)
+	if err != nil {
+		return err
+	}
+	if err := config.ValidateScope(scope); err != nil {
 		return err
 	}
 
-	return downloadFile(artifactID, jarDownloadURL, dest)
-}
-
-// Function to handle GitHub URL
-func HandleGitHubURL(url, libDir string) error {
-	// Example: https://github.com/user/repo/releases/latest/download/file.jar
-	parts := strings.Split(url, "/")
-	if len(parts) < 5 || !strings.HasPrefix(url, "https://github.com") {
-		return fmt.Errorf("invalid GitHub URL format")
+	cfg, err := config.GetTomlConfig()
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s/%s", root.GroupID, root.ArtifactID)
+	if classifier := root.classifier(); classifier != "" {
+		name += ":" + classifier
+	}
+	if scope == "" {
+		scope = cfg.Dependencies[name].Scope
+	}
+	root.Exclusions = cfg.Dependencies[name].Exclude
+	root.Scope = config.Dependency{Scope: scope}.EffectiveScope()
+	roots, err := mavenRoots(cfg)
+	if err != nil {
+		return err
 	}
 
-	// Construct the latest release API URL
-	user := parts[3]
-	repo := parts[4]
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", user, repo)
+	// Resolve the new dependency together with the existing ones so that
+	// conflicts between them are settled before anything is installed.
+	replaced := false
+	for i, existing := range roots {
+		if existing.Key() == root.Key() {
+			roots[i] = root
+			replaced = true
+		}
+	}
+	if !replaced {
+		roots = append(roots, root)
+	}
 
-	// Fetch the latest release info
-	resp, err := http.Get(apiURL)
+	previous, err := lock.Read()
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	graph, artifacts, err := installProject(cfg, roots, libDir, previous)
 	if err != nil {
 		return err
 	}
-	var data GithubRes
-	downloadUrl := ""
 
-	err = json.Unmarshal(body, &data)
-	if err != nil {
+	if err := config.SaveDependency(name, config.Dependency{Origin: "maven", Version: root.Version, Classifier: root.Classifier, Type: root.Type, Exclude: root.Exclusions, Scope: scope}); err != nil {
 		return err
 	}
 
-	for _, asset := range data.Assets {
-		if asset.ContentType == "application/java-archive" {
-			downloadUrl = asset.DonwloadUrl
+	// Artifacts of other origins are not part of the maven graph
+	for key, a := range previous.Artifacts {
+		if _, ok := artifacts[key]; !ok && a.Origin != "maven" {
+			artifacts[key] = a
 		}
 	}
+	return writeLock(graph.Strategy, artifacts)
+}
+
+// HandleGitHubURL installs a github dependency. Urls of a release or of a
+// release download pin the dependency to that tag and asset, any other url
+// to the latest release. The tag that was installed is saved to amber.toml.
+// With build set, or when amber.toml already says so, the dependency is
+// built from source.
+func HandleGitHubURL(url, libDir, scope string, build bool) error {
+	user, repo, tag, asset, err := parseGitHubURL(url)
+	if err != nil {
+		return err
+	}
+	if err := config.ValidateScope(scope); err != nil {
+		return err
+	}
+	cfg, err := config.GetTomlConfig()
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s/%s", user, repo)
+
+	dep := cfg.Dependencies[name]
+	dep.Origin = "github"
+	dep.Tag = tag
+	if asset != "" {
+		dep.Asset = asset
+	}
+	if scope != "" {
+		dep.Scope = scope
+	}
+	if build || dep.Build {
+		dep.Build = true
+		return installGitHubSource(user, repo, dep, cfg, libDir)
+	}
 
-	if downloadUrl == "" {
-		return errors.New("no jar file available in repo")
+	previous, err := lock.Read()
+	if err != nil {
+		return err
+	}
+	artifact, err := installGitHub(user, repo, dep, libDir, previous)
+	if err != nil {
+		return err
 	}
 
-	jarDownloadURL := downloadUrl
-	jarFileName := filepath.Base(jarDownloadURL)
+	dep.Tag = artifact.Version
+	if err := config.SaveDependency(name, dep); err != nil {
+		return err
+	}
 
-	writeJSONLockFile(repo, jarFileName)
+	strategy, err := cfg.ConflictStrategy()
+	if err != nil {
+		return err
+	}
+	previous.Artifacts[artifact.Key()] = artifact
+	return writeLock(strategy, previous.Artifacts)
+}
 
-	if _, err := os.Stat(libDir); os.IsNotExist(err) {
-		os.Mkdir(libDir, os.ModePerm)
+// installGitHubSource adds a github dependency that is built from source
+// and installs the whole project, as the dependencies of the sources join
+// the maven graph.
+func installGitHubSource(user, repo string, dep config.Dependency, cfg *config.Config, libDir string) error {
+	if dep.Tag == "" {
+		release, err := fetchRelease(user, repo, "")
+		if err != nil {
+			return err
+		}
+		dep.Tag = release.TagName
 	}
-	dest := filepath.Join(libDir, jarFileName)
 
-	if err := config.SaveDependency(fmt.Sprintf("%s/%s", user, repo), "github", ""); err != nil {
+	name := fmt.Sprintf("%s/%s", user, repo)
+	existing, declared := cfg.Dependencies[name]
+	if err := config.SaveDependency(name, dep); err != nil {
 		return err
 	}
-	return downloadFile(repo, jarDownloadURL, dest)
+	if err := Install(libDir); err != nil {
+		// Leave amber.toml as it was
+		if declared {
+			config.SaveDependency(name, existing)
+		} else {
+			config.RemoveDependency(name)
+		}
+		return err
+	}
+	return nil
 }
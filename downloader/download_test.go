@@ -0,0 +1,42 @@
+package downloader
+
+import "testing"
+
+func TestParseMavenURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want Artifact
+	}{
+		{"pkg:maven/com.google.guava/guava@33.0.0-jre", Artifact{GroupID: "com.google.guava", ArtifactID: "guava", Version: "33.0.0-jre"}},
+		{"pkg:maven/org.lwjgl/lwjgl@3.3.3?classifier=natives-linux", Artifact{GroupID: "org.lwjgl", ArtifactID: "lwjgl", Version: "3.3.3", Classifier: "natives-linux"}},
+		{"pkg:maven/org.demo/assets@1.0?type=zip", Artifact{GroupID: "org.demo", ArtifactID: "assets", Version: "1.0", Type: "zip"}},
+		{"pkg:maven/org.demo/fixtures@1.0?classifier=tests&type=test-jar", Artifact{GroupID: "org.demo", ArtifactID: "fixtures", Version: "1.0", Classifier: "tests", Type: "test-jar"}},
+		{"pkg:maven/org.demo/app@1.0?type=jar&classifier=linux%2Dx64", Artifact{GroupID: "org.demo", ArtifactID: "app", Version: "1.0", Classifier: "linux-x64", Type: "jar"}},
+		{"pkg:maven/org.demo/app@1.0?", Artifact{GroupID: "org.demo", ArtifactID: "app", Version: "1.0"}},
+	}
+	for _, tt := range tests {
+		got, err := parseMavenURL(tt.url)
+		if err != nil {
+			t.Errorf("parseMavenURL(%s): %v", tt.url, err)
+			continue
+		}
+		if got.String() != tt.want.String() || got.Classifier != tt.want.Classifier || got.Type != tt.want.Type {
+			t.Errorf("parseMavenURL(%s) = %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{
+		"pkg:maven/org.demo/app",
+		"pkg:maven/org.demo/app@1.0@2.0",
+		"pkg:maven/app@1.0",
+		"pkg:maven/org/demo/app@1.0",
+		"pkg:maven//app@1.0",
+		"pkg:maven/org.demo/@1.0",
+		"pkg:maven/org.demo/app@",
+		"pkg:maven/org.demo/app@1.0?classifier=%zz",
+	} {
+		if a, err := parseMavenURL(invalid); err == nil {
+			t.Errorf("parseMavenURL(%s) = %+v, want an error", invalid, a)
+		}
+	}
+}
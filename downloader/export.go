@@ -0,0 +1,268 @@
+package downloader
+
+import (
+	"encoding/xml"
+	"fmt"
+	"jpkg/pkg/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mavenJarPluginVersion is the maven-jar-plugin an exported pom.xml uses to
+// set the main class of the jar.
+const mavenJarPluginVersion = "3.4.2"
+
+// gradleConfigurations maps jpkg scopes to the Gradle configurations with
+// the same meaning.
+var gradleConfigurations = map[string]string{
+	config.ScopeCompile:  "implementation",
+	config.ScopeRuntime:  "runtimeOnly",
+	config.ScopeProvided: "compileOnly",
+	config.ScopeTest:     "testImplementation",
+}
+
+type pomProperty struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+type pomProperties struct {
+	Properties []pomProperty `xml:",any"`
+}
+
+type pomRepository struct {
+	ID  string `xml:"id"`
+	URL string `xml:"url"`
+}
+
+type pomRepositories struct {
+	Repositories []pomRepository `xml:"repository"`
+}
+
+type pomResource struct {
+	Directory string `xml:"directory"`
+}
+
+type pomResources struct {
+	Resources []pomResource `xml:"resource"`
+}
+
+type pomPlugin struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	MainClass  string `xml:"configuration>archive>manifest>mainClass"`
+}
+
+type pomPlugins struct {
+	Plugins []pomPlugin `xml:"plugin"`
+}
+
+type pomBuild struct {
+	SourceDirectory string       `xml:"sourceDirectory"`
+	Resources       pomResources `xml:"resources"`
+	Plugins         *pomPlugins  `xml:"plugins,omitempty"`
+}
+
+// exportCoordinates returns the coordinates of the project, defaulting
+// the ones amber.toml leaves out to the name of the project directory and
+// a snapshot version.
+func exportCoordinates(cfg *config.Config) Artifact {
+	a := Artifact{GroupID: cfg.Group, ArtifactID: cfg.Name, Version: cfg.Version}
+	if a.ArtifactID == "" {
+		a.ArtifactID = "app"
+		if dir, err := os.Getwd(); err == nil {
+			a.ArtifactID = filepath.Base(dir)
+		}
+	}
+	if a.GroupID == "" {
+		a.GroupID = a.ArtifactID
+	}
+	if a.Version == "" {
+		a.Version = "1.0-SNAPSHOT"
+	}
+	return a
+}
+
+// resourcesDir returns the directory jpkg copies resources from, which
+// lives next to the sources.
+func resourcesDir(srcDir string) string {
+	return filepath.ToSlash(filepath.Join(filepath.Dir(srcDir), "resources"))
+}
+
+// ExportMaven renders a pom.xml that builds the project like jpkg does. It
+// also returns what the pom.xml leaves out.
+func ExportMaven(cfg *config.Config, srcDir string) ([]byte, []string, error) {
+	project, skipped, err := projectPOM(cfg, exportCoordinates(cfg))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	properties := []pomProperty{{xml.Name{Local: "project.build.sourceEncoding"}, "UTF-8"}}
+	if cfg.Release != "" {
+		properties = append(properties, pomProperty{xml.Name{Local: "maven.compiler.release"}, cfg.Release})
+	}
+	if cfg.MainClass != "" {
+		properties = append(properties, pomProperty{xml.Name{Local: "exec.mainClass"}, cfg.MainClass})
+	}
+	project.Properties = &pomProperties{properties}
+
+	for _, repository := range cfg.MavenRepositories() {
+		if repository.URL == config.CentralURL {
+			continue
+		}
+		if project.Repositories == nil {
+			project.Repositories = &pomRepositories{}
+		}
+		project.Repositories.Repositories = append(project.Repositories.Repositories, pomRepository{ID: repository.ID, URL: repository.URL})
+	}
+
+	project.Build = &pomBuild{
+		SourceDirectory: filepath.ToSlash(srcDir),
+		Resources:       pomResources{[]pomResource{{Directory: resourcesDir(srcDir)}}},
+	}
+	if cfg.MainClass != "" {
+		project.Build.Plugins = &pomPlugins{[]pomPlugin{{
+			GroupID:    "org.apache.maven.plugins",
+			ArtifactID: "maven-jar-plugin",
+			Version:    mavenJarPluginVersion,
+			MainClass:  cfg.MainClass,
+		}}}
+	}
+
+	content, err := marshalXML(project)
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, skipped, nil
+}
+
+// kotlinString quotes s as a Kotlin string literal.
+func kotlinString(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// gradleNotation returns the dependency notation of a maven dependency,
+// group:name:version with its classifier and extension if it has any.
+func gradleNotation(name string, dep config.Dependency) (string, error) {
+	groupID, artifactID, err := config.SplitMavenName(name)
+	if err != nil {
+		return "", err
+	}
+	notation := fmt.Sprintf("%s:%s:%s", groupID, artifactID, dep.Version)
+	if classifier := config.TypeClassifier(dep.Type, dep.Classifier); classifier != "" {
+		notation += ":" + classifier
+	}
+	if ext := config.TypeExtension(dep.Type); ext != "jar" {
+		notation += "@" + ext
+	}
+	return notation, nil
+}
+
+// ExportGradle renders a build.gradle.kts that builds the project like
+// jpkg does. It also returns what the build script leaves out.
+func ExportGradle(cfg *config.Config, srcDir string) ([]byte, []string, error) {
+	a := exportCoordinates(cfg)
+	var b strings.Builder
+	var skipped []string
+
+	if cfg.MainClass != "" {
+		b.WriteString("plugins {\n    application\n}\n\n")
+	} else {
+		b.WriteString("plugins {\n    java\n}\n\n")
+	}
+	fmt.Fprintf(&b, "group = %s\nversion = %s\n", kotlinString(a.GroupID), kotlinString(a.Version))
+	if cfg.Description != "" {
+		fmt.Fprintf(&b, "description = %s\n", kotlinString(cfg.Description))
+	}
+
+	b.WriteString("\nrepositories {\n")
+	for _, repository := range cfg.MavenRepositories() {
+		if repository.URL == config.CentralURL {
+			b.WriteString("    mavenCentral()\n")
+			continue
+		}
+		fmt.Fprintf(&b, "    maven {\n        name = %s\n        url = uri(%s)\n    }\n", kotlinString(repository.ID), kotlinString(repository.URL))
+	}
+	b.WriteString("}\n")
+
+	if cfg.Release != "" {
+		if _, err := strconv.Atoi(cfg.Release); err != nil {
+			skipped = append(skipped, fmt.Sprintf("release %q, Gradle expects a number", cfg.Release))
+		} else {
+			fmt.Fprintf(&b, "\ntasks.withType<JavaCompile>().configureEach {\n    options.release.set(%s)\n}\n", cfg.Release)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nsourceSets {\n    main {\n        java.setSrcDirs(listOf(%s))\n        resources.setSrcDirs(listOf(%s))\n    }\n}\n",
+		kotlinString(filepath.ToSlash(srcDir)), kotlinString(resourcesDir(srcDir)))
+
+	overrides, err := cfg.VersionOverrides()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(overrides) > 0 {
+		coordinates := make([]string, 0, len(overrides))
+		for coordinate := range overrides {
+			coordinates = append(coordinates, coordinate)
+		}
+		sort.Strings(coordinates)
+		b.WriteString("\nconfigurations.all {\n    resolutionStrategy {\n")
+		for _, coordinate := range coordinates {
+			fmt.Fprintf(&b, "        force(%s)\n", kotlinString(coordinate+":"+overrides[coordinate]))
+		}
+		b.WriteString("    }\n}\n")
+	}
+
+	b.WriteString("\ndependencies {\n")
+	for _, name := range cfg.DependencyNames() {
+		dep := cfg.Dependencies[name]
+		if dep.Origin != "maven" {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", name, dep.Origin))
+			continue
+		}
+		notation, err := gradleNotation(name, dep)
+		if err != nil {
+			return nil, nil, err
+		}
+		configuration, ok := gradleConfigurations[dep.EffectiveScope()]
+		if !ok {
+			return nil, nil, config.ValidateScope(dep.Scope)
+		}
+		if len(dep.Exclude) == 0 {
+			fmt.Fprintf(&b, "    %s(%s)\n", configuration, kotlinString(notation))
+			continue
+		}
+
+		fmt.Fprintf(&b, "    %s(%s) {\n", configuration, kotlinString(notation))
+		for _, pattern := range dep.Exclude {
+			if err := config.ValidateCoordinate(pattern, true); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", name, err)
+			}
+			groupID, artifactID, _ := strings.Cut(pattern, ":")
+			if groupID == "*" && artifactID == "*" {
+				b.WriteString("        isTransitive = false\n")
+				continue
+			}
+			var parts []string
+			if groupID != "*" {
+				parts = append(parts, "group = "+kotlinString(groupID))
+			}
+			if artifactID != "*" {
+				parts = append(parts, "module = "+kotlinString(artifactID))
+			}
+			fmt.Fprintf(&b, "        exclude(%s)\n", strings.Join(parts, ", "))
+		}
+		b.WriteString("    }\n")
+	}
+	b.WriteString("}\n")
+
+	if cfg.MainClass != "" {
+		fmt.Fprintf(&b, "\napplication {\n    mainClass.set(%s)\n}\n", kotlinString(cfg.MainClass))
+	}
+	return []byte(b.String()), skipped, nil
+}
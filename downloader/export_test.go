@@ -0,0 +1,67 @@
+package downloader
+
+import (
+	"jpkg/pkg/config"
+	"strings"
+	"testing"
+)
+
+// testExportConfig declares a dependency of every scope and shape, and a
+// few that a build file cannot express.
+func testExportConfig() *config.Config {
+	cfg := &config.Config{
+		Group:       "org.demo",
+		Name:        "app",
+		Version:     "1.2.0",
+		Description: `Demo "app" for $USER`,
+		MainClass:   "org.demo.Main",
+		Release:     "17",
+	}
+	cfg.AddRepository("company", "https://repo.example.com/maven")
+	cfg.AddDependency("com.google.guava/guava", config.Dependency{Origin: "maven", Version: "33.0.0-jre", Exclude: []string{"com.google.code.findbugs:jsr305", "*:checker-qual", "com.google.errorprone:*"}})
+	cfg.AddDependency("org.demo/natives:natives-linux", config.Dependency{Origin: "maven", Version: "1.0", Classifier: "natives-linux", Scope: config.ScopeRuntime})
+	cfg.AddDependency("org.demo/assets", config.Dependency{Origin: "maven", Version: "1.0", Type: "zip"})
+	cfg.AddDependency("org.demo/fixtures", config.Dependency{Origin: "maven", Version: "1.0", Type: "test-jar", Scope: config.ScopeTest})
+	cfg.AddDependency("org.projectlombok/lombok", config.Dependency{Origin: "maven", Version: "1.18.30", Scope: config.ScopeProvided})
+	cfg.AddDependency("junit/junit", config.Dependency{Origin: "maven", Version: "4.13.2", Scope: config.ScopeTest, Exclude: []string{"*:*"}})
+	cfg.AddDependency("user/tool", config.Dependency{Origin: "github", Tag: "v1.0"})
+	cfg.AddDependency("shared", config.Dependency{Origin: "path", Path: "../shared"})
+	cfg.Overrides = map[string]string{"org.slf4j:slf4j-api": "2.0.9", "com.ex:b": "2.0"}
+	return cfg
+}
+
+func checkSkipped(t *testing.T, skipped []string, want ...string) {
+	t.Helper()
+	if strings.Join(skipped, "\n") != strings.Join(want, "\n") {
+		t.Errorf("skipped %q, want %q", skipped, want)
+	}
+}
+
+func TestExportMaven(t *testing.T) {
+	content, skipped, err := ExportMaven(testExportConfig(), "src/main/java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "export/pom.xml", content)
+	checkSkipped(t, skipped, "user/tool (github)", "shared (path)")
+}
+
+func TestExportGradle(t *testing.T) {
+	content, skipped, err := ExportGradle(testExportConfig(), "src/main/java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "export/build.gradle.kts", content)
+	checkSkipped(t, skipped, "user/tool (github)", "shared (path)")
+}
+
+func TestExportGradleMinimal(t *testing.T) {
+	// Without coordinates the project is named after its directory
+	cfg := &config.Config{Release: "1.8"}
+	content, skipped, err := ExportGradle(cfg, "src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "export/minimal.gradle.kts", content)
+	checkSkipped(t, skipped, `release "1.8", Gradle expects a number`)
+}
@@ -0,0 +1,134 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// fetchRelease reads the release of user/repo with the given tag, or the
+// latest release when tag is empty.
+func fetchRelease(user, repo, tag string) (*GithubRes, error) {
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", user, repo)
+	if tag != "" {
+		apiURL = fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/tags/%s", user, repo, url.PathEscape(tag))
+	}
+
+	resp, err := httpGet(apiURL, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		if tag == "" {
+			return nil, fmt.Errorf("%s/%s has no releases: %w", user, repo, errNotFound)
+		}
+		return nil, fmt.Errorf("release %s of %s/%s: %w", tag, user, repo, errNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpError{URL: apiURL, Status: resp.Status, Code: resp.StatusCode}
+	}
+
+	var release GithubRes
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to read release of %s/%s: %w", user, repo, err)
+	}
+	return &release, nil
+}
+
+// assetMatcher compiles an asset pattern, which is a glob unless it is
+// written as /regexp/.
+func assetMatcher(pattern string) (func(name string) bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid asset pattern %s: %w", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid asset pattern %s: %w", pattern, err)
+	}
+	return func(name string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}, nil
+}
+
+func isJar(asset Asset) bool {
+	return asset.ContentType == "application/java-archive" || strings.HasSuffix(asset.Name, ".jar")
+}
+
+// selectAsset picks the jar to install from the assets of a release.
+// Without a pattern the release has to hold a single jar apart from
+// sources and javadoc jars.
+func selectAsset(release *GithubRes, pattern string) (Asset, error) {
+	var candidates []Asset
+	if pattern != "" {
+		matches, err := assetMatcher(pattern)
+		if err != nil {
+			return Asset{}, err
+		}
+		for _, asset := range release.Assets {
+			if matches(asset.Name) {
+				candidates = append(candidates, asset)
+			}
+		}
+	} else {
+		for _, asset := range release.Assets {
+			if isJar(asset) && !strings.HasSuffix(asset.Name, "-sources.jar") && !strings.HasSuffix(asset.Name, "-javadoc.jar") {
+				candidates = append(candidates, asset)
+			}
+		}
+	}
+
+	var names []string
+	for _, asset := range candidates {
+		names = append(names, asset.Name)
+	}
+	switch {
+	case len(candidates) == 1:
+		return candidates[0], nil
+	case len(candidates) == 0 && pattern != "":
+		return Asset{}, fmt.Errorf("no asset of release %s matches %s", release.TagName, pattern)
+	case len(candidates) == 0:
+		return Asset{}, fmt.Errorf("release %s has no jar file", release.TagName)
+	default:
+		return Asset{}, fmt.Errorf("release %s has several matching assets (%s), set asset to pick one", release.TagName, strings.Join(names, ", "))
+	}
+}
+
+// parseGitHubURL reads the repository of a github url and, when the url
+// points at a release or a release download, its tag and asset name.
+func parseGitHubURL(githubURL string) (user, repo, tag, asset string, err error) {
+	if !strings.HasPrefix(githubURL, "https://github.com/") {
+		return "", "", "", "", fmt.Errorf("invalid GitHub URL format")
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(githubURL, "https://github.com/"), "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", "", fmt.Errorf("invalid GitHub URL format")
+	}
+	user, repo = parts[0], strings.TrimSuffix(parts[1], ".git")
+
+	rest := parts[2:]
+	switch {
+	case len(rest) >= 3 && rest[0] == "releases" && rest[1] == "tag":
+		// https://github.com/user/repo/releases/tag/v1.0
+		tag = rest[2]
+	case len(rest) >= 4 && rest[0] == "releases" && rest[1] == "download":
+		// https://github.com/user/repo/releases/download/v1.0/file.jar
+		tag, asset = rest[2], rest[3]
+	case len(rest) >= 4 && rest[0] == "releases" && rest[1] == "latest" && rest[2] == "download":
+		// https://github.com/user/repo/releases/latest/download/file.jar
+		asset = rest[3]
+	}
+	if tag, err = url.PathUnescape(tag); err != nil {
+		return "", "", "", "", fmt.Errorf("invalid GitHub URL format")
+	}
+	return user, repo, tag, asset, nil
+}
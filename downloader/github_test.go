@@ -0,0 +1,101 @@
+package downloader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGitHubURL(t *testing.T) {
+	tests := []struct {
+		url                    string
+		user, repo, tag, asset string
+	}{
+		{"https://github.com/user/repo", "user", "repo", "", ""},
+		{"https://github.com/user/repo/", "user", "repo", "", ""},
+		{"https://github.com/user/repo.git", "user", "repo", "", ""},
+		{"https://github.com/user/repo/tree/main", "user", "repo", "", ""},
+		{"https://github.com/user/repo/releases", "user", "repo", "", ""},
+		{"https://github.com/user/repo/releases/tag/v1.2.0", "user", "repo", "v1.2.0", ""},
+		{"https://github.com/user/repo/releases/tag/release%2F1.0", "user", "repo", "release/1.0", ""},
+		{"https://github.com/user/repo/releases/download/v1.2.0/repo-1.2.0-all.jar", "user", "repo", "v1.2.0", "repo-1.2.0-all.jar"},
+		{"https://github.com/user/repo/releases/latest/download/repo.jar", "user", "repo", "", "repo.jar"},
+	}
+	for _, tt := range tests {
+		user, repo, tag, asset, err := parseGitHubURL(tt.url)
+		if err != nil {
+			t.Errorf("parseGitHubURL(%s): %v", tt.url, err)
+			continue
+		}
+		if user != tt.user || repo != tt.repo || tag != tt.tag || asset != tt.asset {
+			t.Errorf("parseGitHubURL(%s) = %q, %q, %q, %q, want %q, %q, %q, %q", tt.url, user, repo, tag, asset, tt.user, tt.repo, tt.tag, tt.asset)
+		}
+	}
+
+	for _, invalid := range []string{
+		"http://github.com/user/repo",
+		"https://gitlab.com/user/repo",
+		"https://github.com/user",
+		"https://github.com//repo",
+		"https://github.com/user/repo/releases/tag/%zz",
+	} {
+		if _, _, _, _, err := parseGitHubURL(invalid); err == nil {
+			t.Errorf("parseGitHubURL(%s) was accepted", invalid)
+		}
+	}
+}
+
+func TestSelectAsset(t *testing.T) {
+	release := &GithubRes{TagName: "v1.0", Assets: []Asset{
+		{Name: "tool-1.0.jar", ContentType: "application/java-archive"},
+		{Name: "tool-1.0-sources.jar", ContentType: "application/java-archive"},
+		{Name: "tool-1.0-javadoc.jar", ContentType: "application/java-archive"},
+		{Name: "tool-1.0.zip", ContentType: "application/zip"},
+		{Name: "tool-native", ContentType: "application/java-archive"},
+	}}
+	several := &GithubRes{TagName: "v2.0", Assets: []Asset{
+		{Name: "tool-2.0.jar", ContentType: "application/octet-stream"},
+		{Name: "tool-2.0-all.jar", ContentType: "application/octet-stream"},
+		{Name: "tool-2.0-sources.jar", ContentType: "application/octet-stream"},
+	}}
+	none := &GithubRes{TagName: "v3.0", Assets: []Asset{{Name: "tool-3.0.tar.gz"}}}
+
+	tests := []struct {
+		name    string
+		release *GithubRes
+		pattern string
+		want    string
+		err     string
+	}{
+		{"glob picks one of several jars", several, "*-all.jar", "tool-2.0-all.jar", ""},
+		{"by content type", release, "", "", "several matching assets (tool-1.0.jar, tool-native)"},
+		{"glob", release, "tool-*.zip", "tool-1.0.zip", ""},
+		{"glob matching several", several, "tool-2.0*.jar", "", "several matching assets (tool-2.0.jar, tool-2.0-all.jar, tool-2.0-sources.jar)"},
+		{"regexp", several, `/^tool-[0-9.]+\.jar$/`, "tool-2.0.jar", ""},
+		{"regexp is not anchored", several, "/all/", "tool-2.0-all.jar", ""},
+		{"several jars", several, "", "", "set asset to pick one"},
+		{"no match", release, "*.war", "", "no asset of release v1.0 matches *.war"},
+		{"no jar", none, "", "", "release v3.0 has no jar file"},
+		{"invalid glob", release, "[", "", "invalid asset pattern ["},
+		{"invalid regexp", release, "/(/", "", "invalid asset pattern /(/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset, err := selectAsset(tt.release, tt.pattern)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("selectAsset(%q) error = %v, want %q", tt.pattern, err, tt.err)
+				}
+				return
+			}
+			if err != nil || asset.Name != tt.want {
+				t.Errorf("selectAsset(%q) = %s, %v, want %s", tt.pattern, asset.Name, err, tt.want)
+			}
+		})
+	}
+
+	// Without sources and javadoc a single jar is picked on its own
+	release.Assets = release.Assets[:4]
+	if asset, err := selectAsset(release, ""); err != nil || asset.Name != "tool-1.0.jar" {
+		t.Errorf("selectAsset() = %s, %v", asset.Name, err)
+	}
+}
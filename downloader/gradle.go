@@ -0,0 +1,541 @@
+package downloader
+
+import (
+	"bufio"
+	"fmt"
+	"jpkg/pkg/config"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// gradleScopes maps Gradle configurations to the jpkg scopes with the same
+// meaning.
+var gradleScopes = map[string]string{
+	"api":                config.ScopeCompile,
+	"implementation":     config.ScopeCompile,
+	"compile":            config.ScopeCompile,
+	"compileOnly":        config.ScopeProvided,
+	"compileOnlyApi":     config.ScopeProvided,
+	"runtimeOnly":        config.ScopeRuntime,
+	"runtime":            config.ScopeRuntime,
+	"testImplementation": config.ScopeTest,
+	"testCompileOnly":    config.ScopeTest,
+	"testRuntimeOnly":    config.ScopeTest,
+	"testCompile":        config.ScopeTest,
+	"testRuntime":        config.ScopeTest,
+}
+
+// translatedGradlePlugins are the plugins whose settings end up in
+// amber.toml.
+var translatedGradlePlugins = map[string]bool{"java": true, "java-library": true, "application": true}
+
+var (
+	gradleVariable      = regexp.MustCompile(`^\s*(?:val|var|def)?\s*(?:ext\.|extra\[["'])?([A-Za-z_][\w.]*)(?:["']\])?\s*=\s*["']([^"'$]*)["']\s*$`)
+	gradleReference     = regexp.MustCompile(`\$\{?([A-Za-z_][\w.]*)\}?`)
+	gradleString        = regexp.MustCompile(`["']([^"']*)["']`)
+	gradleBlock         = regexp.MustCompile(`^\s*([\w.]+)\s*\{\s*$`)
+	gradleCall          = regexp.MustCompile(`^\s*(\w+)\s*[( ]\s*(.*?)\s*\)?\s*(\{)?\s*$`)
+	gradleNamedArg      = regexp.MustCompile(`(group|name|module|version|classifier|ext)\s*[:=]\s*["']([^"']*)["']`)
+	gradleLibrary       = regexp.MustCompile(`^libs\.([\w.]+)$`)
+	gradlePluginID      = regexp.MustCompile(`^\s*(?:id\s*\(?\s*["']([^"']+)["']|` + "`" + `?([\w-]+)` + "`" + `?\s*$|(\w+)\s*\()`)
+	gradleMavenURL      = regexp.MustCompile(`url\s*=?\s*(?:uri\()?\s*["']([^"']+)["']|^\s*maven\s*\(\s*(?:url\s*=\s*)?(?:uri\()?\s*["']([^"']+)["']`)
+	gradleRepoName      = regexp.MustCompile(`name\s*=?\s*["']([^"']+)["']`)
+	gradleForce         = regexp.MustCompile(`force\s*\(?\s*["']([^"':]+):([^"':]+):([^"']+)["']`)
+	gradleTransitive    = regexp.MustCompile(`(?:isTransitive|transitive)\s*=\s*false`)
+	gradleRootName      = regexp.MustCompile(`rootProject\.name\s*=\s*["']([^"']+)["']`)
+	gradleMainClass     = regexp.MustCompile(`mainClass(?:Name)?\s*(?:\.set\(\s*|=\s*)["']([^"']+)["']`)
+	gradleSrcDir        = regexp.MustCompile(`java\s*(?:\.|\{\s*)(?:setSrcDirs|srcDirs?)\s*[(=]?\s*(?:listOf\(|\[)?\s*["']([^"']+)["']`)
+	gradleRelease       = regexp.MustCompile(`(?:options\.release(?:\.set\(|\s*=)\s*|JavaLanguageVersion\.of\(\s*)(\d+)`)
+	gradleCompatibility = regexp.MustCompile(`(?:sourceCompatibility|targetCompatibility)\s*=\s*(?:JavaVersion\.VERSION_([\d_]+)|["']?([\d.]+)["']?)`)
+	gradleProperty      = regexp.MustCompile(`(?m)^(group|version|description)\s*=\s*["']([^"']*)["']`)
+)
+
+// gradleBuild is a build script split into its top-level blocks.
+type gradleBuild struct {
+	text      string
+	blocks    map[string][]string
+	variables map[string]string
+}
+
+// readGradleBuild reads the build script at path and the variables it
+// declares, including the ones of gradle.properties next to it.
+func readGradleBuild(path string) (*gradleBuild, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	build := &gradleBuild{text: string(content), blocks: map[string][]string{}, variables: map[string]string{}}
+
+	if properties, err := os.ReadFile(filepath.Join(filepath.Dir(path), "gradle.properties")); err == nil {
+		for _, line := range strings.Split(string(properties), "\n") {
+			key, value, ok := strings.Cut(line, "=")
+			if ok && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+				build.variables[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	depth, block := 0, ""
+	scanner := bufio.NewScanner(strings.NewReader(build.text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 && (i == 0 || line[i-1] != ':') {
+			line = line[:i]
+		}
+		if match := gradleVariable.FindStringSubmatch(line); match != nil {
+			build.variables[match[1]] = match[2]
+		}
+		if depth == 0 {
+			if match := gradleBlock.FindStringSubmatch(line); match != nil {
+				block = match[1]
+				depth = 1
+				continue
+			}
+		}
+		if depth > 0 {
+			depth += strings.Count(line, "{") - strings.Count(line, "}")
+			if depth <= 0 {
+				depth, block = 0, ""
+				continue
+			}
+			build.blocks[block] = append(build.blocks[block], line)
+		}
+	}
+	return build, scanner.Err()
+}
+
+// expand replaces $name and ${name} references with the variables of the
+// build. It reports whether every reference could be resolved.
+func (b *gradleBuild) expand(value string) (string, bool) {
+	resolved := true
+	value = gradleReference.ReplaceAllStringFunc(value, func(reference string) string {
+		name := gradleReference.FindStringSubmatch(reference)[1]
+		if variable, ok := b.variables[strings.TrimPrefix(name, "project.")]; ok {
+			return variable
+		}
+		resolved = false
+		return reference
+	})
+	return value, resolved
+}
+
+// find returns the first group of pattern that matched the build script.
+func (b *gradleBuild) find(pattern *regexp.Regexp) string {
+	match := pattern.FindStringSubmatch(b.text)
+	for _, group := range match[min(1, len(match)):] {
+		if group != "" {
+			value, _ := b.expand(group)
+			return value
+		}
+	}
+	return ""
+}
+
+// versionCatalog is a gradle/libs.versions.toml file.
+type versionCatalog struct {
+	Versions  map[string]any      `toml:"versions"`
+	Libraries map[string]any      `toml:"libraries"`
+	Bundles   map[string][]string `toml:"bundles"`
+}
+
+func readVersionCatalog(path string) (*versionCatalog, error) {
+	var catalog versionCatalog
+	if _, err := toml.DecodeFile(path, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &catalog, nil
+}
+
+// catalogAlias turns an alias into the form Gradle generates accessors
+// from, where -, _ and . all separate words.
+func catalogAlias(alias string) string {
+	return strings.NewReplacer("-", ".", "_", ".").Replace(alias)
+}
+
+// catalogVersion reads a version given as a string or as a table of rich
+// version constraints.
+func catalogVersion(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]any:
+		for _, key := range []string{"strictly", "require", "prefer"} {
+			if version, ok := v[key].(string); ok {
+				return version
+			}
+		}
+	}
+	return ""
+}
+
+// collision reports the aliases that share an accessor, which Gradle
+// refuses as it cannot tell them apart.
+func collision(kind, accessor string, aliases []string) error {
+	sort.Strings(aliases)
+	return fmt.Errorf("catalog %s %s collide as %s", kind, strings.Join(aliases, " and "), accessor)
+}
+
+// library returns the coordinates of the catalog entry with the given
+// accessor, such as commons.lang3 for commons-lang3.
+func (c *versionCatalog) library(accessor string) (string, string, string, error) {
+	var aliases []string
+	for alias := range c.Libraries {
+		if catalogAlias(alias) == accessor {
+			aliases = append(aliases, alias)
+		}
+	}
+	switch {
+	case len(aliases) == 0:
+		return "", "", "", fmt.Errorf("libs.%s is not in the version catalog", accessor)
+	case len(aliases) > 1:
+		return "", "", "", collision("libraries", "libs."+accessor, aliases)
+	}
+
+	alias := aliases[0]
+	var module, version string
+	switch e := c.Libraries[alias].(type) {
+	case string:
+		parts := strings.Split(e, ":")
+		if len(parts) == 3 {
+			module, version = parts[0]+":"+parts[1], parts[2]
+		} else {
+			module = e
+		}
+	case map[string]any:
+		module, _ = e["module"].(string)
+		if group, ok := e["group"].(string); ok {
+			name, _ := e["name"].(string)
+			module = group + ":" + name
+		}
+		version = catalogVersion(e["version"])
+		if constraint, ok := e["version"].(map[string]any); ok {
+			if ref, ok := constraint["ref"].(string); ok {
+				version = catalogVersion(c.Versions[ref])
+			}
+		}
+	}
+	groupID, artifactID, ok := strings.Cut(module, ":")
+	if !ok || groupID == "" || artifactID == "" {
+		return "", "", "", fmt.Errorf("catalog library %s has no module", alias)
+	}
+	if version == "" {
+		return "", "", "", fmt.Errorf("catalog library %s has no version", alias)
+	}
+	return groupID, artifactID, version, nil
+}
+
+// bundle returns the accessors of the libraries in a catalog bundle.
+func (c *versionCatalog) bundle(accessor string) ([]string, error) {
+	var aliases []string
+	for alias := range c.Bundles {
+		if catalogAlias(alias) == accessor {
+			aliases = append(aliases, alias)
+		}
+	}
+	switch {
+	case len(aliases) == 0:
+		return nil, fmt.Errorf("libs.bundles.%s is not in the version catalog", accessor)
+	case len(aliases) > 1:
+		return nil, collision("bundles", "libs.bundles."+accessor, aliases)
+	}
+
+	libraries := c.Bundles[aliases[0]]
+	accessors := make([]string, len(libraries))
+	for i, library := range libraries {
+		accessors[i] = catalogAlias(library)
+	}
+	return accessors, nil
+}
+
+// gradleDependency parses a group:name:version[:classifier][@ext] notation.
+func gradleDependency(notation string) (string, string, config.Dependency, bool) {
+	notation, ext, _ := strings.Cut(notation, "@")
+	parts := strings.Split(notation, ":")
+	if len(parts) < 3 || len(parts) > 4 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", config.Dependency{}, false
+	}
+	dep := config.Dependency{Origin: "maven", Version: parts[2], Type: ext}
+	if len(parts) == 4 {
+		dep.Classifier = parts[3]
+	}
+	return parts[0], parts[1], dep, true
+}
+
+// importGradleDependencies translates the top-level dependencies block.
+func importGradleDependencies(imported *Imported, build *gradleBuild, catalog *versionCatalog) {
+	var last *config.Dependency
+	var lastName string
+	depth := 0
+	for _, line := range build.blocks["dependencies"] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if depth > 0 {
+			depth += strings.Count(line, "{") - strings.Count(line, "}")
+			switch {
+			case trimmed == "}":
+			case strings.HasPrefix(trimmed, "exclude") && last != nil:
+				groupID, artifactID := "*", "*"
+				for _, match := range gradleNamedArg.FindAllStringSubmatch(trimmed, -1) {
+					switch match[1] {
+					case "group":
+						groupID = match[2]
+					case "module", "name":
+						artifactID = match[2]
+					}
+				}
+				last.Exclude = append(last.Exclude, groupID+":"+artifactID)
+			case gradleTransitive.MatchString(trimmed) && last != nil:
+				last.Exclude = append(last.Exclude, "*:*")
+			default:
+				imported.skip("%s of %s", trimmed, lastName)
+			}
+			if depth == 0 && last != nil {
+				imported.Config.Dependencies[lastName] = *last
+				last = nil
+			}
+			continue
+		}
+
+		match := gradleCall.FindStringSubmatch(line)
+		if match == nil {
+			imported.skip("dependency line %s", trimmed)
+			continue
+		}
+		configuration, argument, opens := match[1], match[2], match[3] != ""
+		if opens {
+			depth, last, lastName = 1, nil, argument
+		}
+		scope, ok := gradleScopes[configuration]
+		if !ok {
+			imported.skip("%s dependency %s", configuration, argument)
+			continue
+		}
+
+		var coordinates [][3]string
+		var dependency config.Dependency
+		switch {
+		case gradleLibrary.MatchString(argument):
+			accessor := gradleLibrary.FindStringSubmatch(argument)[1]
+			if catalog == nil {
+				imported.skip("dependency %s, no version catalog was found", argument)
+				continue
+			}
+			accessors := []string{accessor}
+			if bundle, ok := strings.CutPrefix(accessor, "bundles."); ok {
+				var err error
+				if accessors, err = catalog.bundle(bundle); err != nil {
+					imported.skip("%v", err)
+					continue
+				}
+			}
+			for _, accessor := range accessors {
+				groupID, artifactID, version, err := catalog.library(accessor)
+				if err != nil {
+					imported.skip("%v", err)
+					continue
+				}
+				coordinates = append(coordinates, [3]string{groupID, artifactID, version})
+			}
+		case gradleNamedArg.MatchString(argument):
+			named := map[string]string{}
+			for _, arg := range gradleNamedArg.FindAllStringSubmatch(argument, -1) {
+				named[arg[1]], _ = build.expand(arg[2])
+			}
+			if named["group"] == "" || named["name"] == "" || named["version"] == "" {
+				imported.skip("dependency %s", argument)
+				continue
+			}
+			dependency = config.Dependency{Origin: "maven", Classifier: named["classifier"], Type: named["ext"]}
+			coordinates = append(coordinates, [3]string{named["group"], named["name"], named["version"]})
+		default:
+			strs := gradleString.FindStringSubmatch(argument)
+			if strs == nil || strings.Contains(argument, "(") {
+				imported.skip("dependency %s", trimmed)
+				continue
+			}
+			notation, resolved := build.expand(strs[1])
+			groupID, artifactID, dep, ok := gradleDependency(notation)
+			if !ok || !resolved {
+				imported.skip("%s dependency %s", configuration, notation)
+				continue
+			}
+			dependency = dep
+			coordinates = append(coordinates, [3]string{groupID, artifactID, dep.Version})
+		}
+
+		for _, coordinate := range coordinates {
+			dep := dependency
+			dep.Origin, dep.Version, dep.Scope = "maven", coordinate[2], scope
+			imported.addDependency(coordinate[0], coordinate[1], dep)
+		}
+		// Exclusions in the block apply to the last dependency declared
+		if opens && len(coordinates) == 1 {
+			names := imported.Config.DependencyNames()
+			lastName = names[len(names)-1]
+			dep := imported.Config.Dependencies[lastName]
+			last = &dep
+		}
+	}
+}
+
+// ImportGradle translates the build.gradle or build.gradle.kts at path,
+// with the gradle/libs.versions.toml catalog next to it. Only simple
+// builds are understood: the top-level plugins, repositories and
+// dependencies blocks and the usual java and application settings.
+func ImportGradle(path string) (*Imported, error) {
+	build, err := readGradleBuild(path)
+	if err != nil {
+		return nil, err
+	}
+	var catalog *versionCatalog
+	catalogPath := filepath.Join(filepath.Dir(path), "gradle", "libs.versions.toml")
+	if _, err := os.Stat(catalogPath); err == nil {
+		if catalog, err = readVersionCatalog(catalogPath); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := &config.Config{}
+	imported := &Imported{Config: cfg}
+	for _, match := range gradleProperty.FindAllStringSubmatch(build.text, -1) {
+		value, _ := build.expand(match[2])
+		switch match[1] {
+		case "group":
+			cfg.Group = value
+		case "version":
+			cfg.Version = value
+		case "description":
+			cfg.Description = value
+		}
+	}
+	for _, settings := range []string{"settings.gradle.kts", "settings.gradle"} {
+		if content, err := os.ReadFile(filepath.Join(filepath.Dir(path), settings)); err == nil {
+			if match := gradleRootName.FindStringSubmatch(string(content)); match != nil {
+				cfg.Name = match[1]
+			}
+			break
+		}
+	}
+	if cfg.Name == "" {
+		if dir, err := filepath.Abs(filepath.Dir(path)); err == nil {
+			cfg.Name = filepath.Base(dir)
+		}
+	}
+
+	for _, line := range build.blocks["plugins"] {
+		match := gradlePluginID.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		id := match[1] + match[2] + match[3]
+		if !translatedGradlePlugins[id] {
+			imported.skip("plugin %s", id)
+		}
+	}
+
+	cfg.MainClass = build.find(gradleMainClass)
+	cfg.Release = build.find(gradleRelease)
+	if cfg.Release == "" {
+		cfg.Release = javaRelease(strings.ReplaceAll(build.find(gradleCompatibility), "_", "."))
+	}
+	srcDir := "src/main/java"
+	if dir := build.find(gradleSrcDir); dir != "" {
+		srcDir = filepath.ToSlash(filepath.Clean(dir))
+	}
+	if srcDir != "src" {
+		cfg.SrcDir = srcDir
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), "src", "test", "java")); err == nil {
+		imported.skip("test sources in src/test/java, jpkg does not compile tests")
+	}
+
+	repositories := build.blocks["repositories"]
+	for i := 0; i < len(repositories); i++ {
+		line := strings.TrimSpace(repositories[i])
+		switch {
+		case line == "" || line == "}" || strings.HasPrefix(line, "mavenCentral"):
+		case strings.HasPrefix(line, "google"):
+			cfg.AddRepository("google", "https://maven.google.com")
+		case strings.HasPrefix(line, "maven"):
+			// maven { url = uri("...") } may span several lines
+			text := line
+			for strings.HasSuffix(line, "{") && i+1 < len(repositories) && !strings.Contains(text, "}") {
+				i++
+				text += " " + strings.TrimSpace(repositories[i])
+			}
+			match := gradleMavenURL.FindStringSubmatch(text)
+			if match == nil {
+				imported.skip("repository %s", text)
+				continue
+			}
+			url, _ := build.expand(match[1] + match[2])
+			url = strings.TrimSuffix(url, "/")
+			id := strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+			id, _, _ = strings.Cut(id, "/")
+			if name := gradleRepoName.FindStringSubmatch(strings.Replace(text, match[0], "", 1)); name != nil {
+				id = name[1]
+			}
+			cfg.AddRepository(id, url)
+		default:
+			imported.skip("repository %s", line)
+		}
+	}
+
+	for _, match := range gradleForce.FindAllStringSubmatch(build.text, -1) {
+		version, ok := build.expand(match[3])
+		if !ok {
+			imported.skip("forced version %s:%s:%s", match[1], match[2], match[3])
+			continue
+		}
+		if cfg.Overrides == nil {
+			cfg.Overrides = map[string]string{}
+		}
+		cfg.Overrides[match[1]+":"+match[2]] = version
+	}
+
+	importGradleDependencies(imported, build, catalog)
+	return imported, nil
+}
+
+// ImportCatalog translates a libs.versions.toml version catalog on its own.
+// Its libraries become compile dependencies, as the catalog does not say
+// how they are used.
+func ImportCatalog(path string) (*Imported, error) {
+	catalog, err := readVersionCatalog(path)
+	if err != nil {
+		return nil, err
+	}
+	imported := &Imported{Config: &config.Config{}}
+
+	aliases := make([]string, 0, len(catalog.Libraries))
+	for alias := range catalog.Libraries {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	seen := map[string]bool{}
+	for _, alias := range aliases {
+		// Colliding aliases are reported once
+		accessor := catalogAlias(alias)
+		if seen[accessor] {
+			continue
+		}
+		seen[accessor] = true
+		groupID, artifactID, version, err := catalog.library(accessor)
+		if err != nil {
+			imported.skip("%v", err)
+			continue
+		}
+		imported.addDependency(groupID, artifactID, config.Dependency{Origin: "maven", Version: version})
+	}
+	if len(aliases) > 0 {
+		imported.skip("scopes of the catalog libraries, they are all compile dependencies")
+	}
+	return imported, nil
+}
@@ -0,0 +1,603 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"jpkg/pkg/config"
+	"jpkg/pkg/lock"
+	"jpkg/pom"
+	"strings"
+)
+
+type Node struct {
+	Artifact
+	Packaging string
+	Depth     int
+	// Overridden is set when the version was forced by [overrides].
+	Overridden bool
+	// Dependencies holds what this artifact's pom asks for, with the
+	// requested (not necessarily selected) versions.
+	Dependencies []Artifact
+	// excluded holds the exclusions of every artifact on the path that
+	// led here.
+	excluded []string
+}
+
+// Excluded is a dependency that an exclusion kept out of the graph.
+type Excluded struct {
+	Artifact
+	// From is the artifact that asked for it.
+	From Artifact
+}
+
+// Graph is the resolved dependency graph of a project. Only one version of
+// each groupId:artifactId is ever selected.
+type Graph struct {
+	Roots        []Artifact
+	Nodes        map[string]*Node
+	Order        []string
+	Excluded     []Excluded
+	Strategy     string
+	Repositories []config.Repository
+}
+
+// BuiltFromSource reports whether jpkg builds the artifact itself rather
+// than downloading it.
+func (n *Node) BuiltFromSource() bool {
+	return n.Packaging == packagingSource
+}
+
+func (g *Graph) Selected(key string) (*Node, bool) {
+	node, ok := g.Nodes[key]
+	return node, ok
+}
+
+// Paths returns every chain of artifacts leading from a root to the
+// artifact with the given key. Each element carries the version that was
+// requested at that point of the chain.
+func (g *Graph) Paths(key string) [][]Artifact {
+	var paths [][]Artifact
+	var walk func(path []Artifact, visiting map[string]bool)
+	walk = func(path []Artifact, visiting map[string]bool) {
+		last := path[len(path)-1]
+		if last.Key() == key {
+			paths = append(paths, append([]Artifact(nil), path...))
+			return
+		}
+
+		node, ok := g.Nodes[last.Key()]
+		if !ok || (node.Version != last.Version && !node.Overridden) {
+			// Only the selected version's dependencies are part of the graph
+			return
+		}
+		for _, dep := range node.Dependencies {
+			if visiting[dep.Key()] {
+				continue
+			}
+			visiting[dep.Key()] = true
+			walk(append(path, dep), visiting)
+			delete(visiting, dep.Key())
+		}
+	}
+
+	for _, root := range g.Roots {
+		walk([]Artifact{root}, map[string]bool{root.Key(): true})
+	}
+	return paths
+}
+
+// Transitive returns the keys of every selected artifact the artifact with
+// the given key depends on, directly or not.
+func (g *Graph) Transitive(key string) []string {
+	var keys []string
+	seen := map[string]bool{key: true}
+	queue := []string{key}
+	for len(queue) > 0 {
+		node, ok := g.Nodes[queue[0]]
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+		for _, dep := range node.Dependencies {
+			if !seen[dep.Key()] {
+				seen[dep.Key()] = true
+				keys = append(keys, dep.Key())
+				queue = append(queue, dep.Key())
+			}
+		}
+	}
+	return keys
+}
+
+// Find returns the keys of the selected artifacts matching name, which is
+// either groupId:artifactId, groupId/artifactId or a bare artifactId.
+func (g *Graph) Find(name string) []string {
+	name = strings.Replace(name, "/", ":", 1)
+	if _, ok := g.Nodes[name]; ok {
+		return []string{name}
+	}
+
+	var keys []string
+	for _, key := range g.Order {
+		if g.Nodes[key].ArtifactID == name {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// FindExcluded returns the dependencies matching name that were excluded,
+// with name as in Find.
+func (g *Graph) FindExcluded(name string) []Excluded {
+	name = strings.Replace(name, "/", ":", 1)
+	var found []Excluded
+	for _, excluded := range g.Excluded {
+		if excluded.Key() == name || excluded.ArtifactID == name {
+			found = append(found, excluded)
+		}
+	}
+	return found
+}
+
+// exclude drops the dependencies ruled out by the exclusions that apply to
+// node and records them in the graph.
+func (g *Graph) exclude(node *Node, deps []Artifact) []Artifact {
+	var kept []Artifact
+	for _, dep := range deps {
+		if excludes(node.excluded, dep) {
+			g.Excluded = append(g.Excluded, Excluded{Artifact: dep, From: node.Artifact})
+			continue
+		}
+		kept = append(kept, dep)
+	}
+	return kept
+}
+
+// lockEdges returns the dependencies of node the way the lock file records
+// them, followed by the ones an exclusion kept out of the graph.
+func (g *Graph) lockEdges(node *Node) ([]string, []string) {
+	var deps, excluded []string
+	for _, dep := range node.Dependencies {
+		deps = append(deps, dep.String())
+	}
+	for _, dep := range g.Excluded {
+		if dep.From.Key() == node.Key() {
+			excluded = append(excluded, dep.Artifact.String())
+		}
+	}
+	return deps, excluded
+}
+
+type graphResolver struct {
+	poms *pom.Resolver
+	// sources holds the dependencies of artifacts built from source,
+	// which have no pom.
+	sources map[string][]Artifact
+	// overrides holds the versions forced by groupId:artifactId.
+	overrides map[string]string
+}
+
+// walk visits the graph breadth first. Versions in pinned take precedence,
+// everything else is settled by the first (nearest) request. It also
+// reports every version that was requested for each artifact. Any pom
+// that cannot be resolved fails the walk.
+func (r *graphResolver) walk(roots []Artifact, pinned map[string]string) (*Graph, map[string][]string, error) {
+	graph := &Graph{Roots: roots, Nodes: map[string]*Node{}}
+	requested := map[string][]string{}
+	failed := map[string]bool{}
+
+	var queue []*Node
+	enqueue := func(a Artifact, depth int, excluded []string) {
+		requested[a.Key()] = append(requested[a.Key()], a.Version)
+		if _, ok := graph.Nodes[a.Key()]; ok || failed[a.Key()] {
+			return
+		}
+		overridden := false
+		if version, ok := r.overrides[a.GroupID+":"+a.ArtifactID]; ok {
+			a.Version = version
+			overridden = true
+		} else if version, ok := pinned[a.Key()]; ok {
+			a.Version = version
+		}
+		node := &Node{Artifact: a, Depth: depth, Overridden: overridden}
+		node.excluded = append(append(node.excluded, excluded...), a.Exclusions...)
+		graph.Nodes[a.Key()] = node
+		graph.Order = append(graph.Order, a.Key())
+		queue = append(queue, node)
+	}
+
+	for _, root := range roots {
+		enqueue(root, 0, nil)
+	}
+
+	var missing, unresolved []string
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if deps, ok := r.sources[node.Key()]; ok {
+			node.Packaging = packagingSource
+			node.Dependencies = graph.exclude(node, deps)
+			for _, dep := range node.Dependencies {
+				enqueue(dep, node.Depth+1, node.excluded)
+			}
+			continue
+		}
+
+		project, err := r.poms.Effective(node.GroupID, node.ArtifactID, node.Version)
+		if errors.Is(err, errOffline) {
+			missing = append(missing, node.Artifact.String()+" (pom)")
+			continue
+		}
+		if err != nil {
+			if node.Depth == 0 {
+				return nil, nil, err
+			}
+			// A missing pom means missing jars at runtime, so keep going
+			// only to report every one of them
+			unresolved = append(unresolved, fmt.Sprintf("%s: %v", node.Artifact, err))
+			delete(graph.Nodes, node.Key())
+			failed[node.Key()] = true
+			continue
+		}
+
+		node.Packaging = project.Packaging
+		node.Dependencies = graph.exclude(node, runtimeDependencies(project))
+		for _, dep := range node.Dependencies {
+			enqueue(dep, node.Depth+1, node.excluded)
+		}
+	}
+
+	var errs []error
+	if len(unresolved) > 0 {
+		errs = append(errs, fmt.Errorf("failed to resolve the poms of\n  %s", strings.Join(unresolved, "\n  ")))
+	}
+	if len(missing) > 0 {
+		errs = append(errs, offlineError(missing))
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	graph.assignScopes()
+	return graph, requested, nil
+}
+
+// scopeRank orders scopes from narrowest to widest. An artifact reached
+// through several paths keeps the widest scope.
+var scopeRank = map[string]int{
+	config.ScopeTest:     1,
+	config.ScopeProvided: 2,
+	config.ScopeRuntime:  3,
+	config.ScopeCompile:  4,
+}
+
+// transitiveScope returns the scope of a dependency declared with the
+// given scope when it is reached through an artifact in parent scope,
+// following Maven's scope table.
+func transitiveScope(parent, declared string) string {
+	if parent == config.ScopeCompile && declared == config.ScopeRuntime {
+		return config.ScopeRuntime
+	}
+	return parent
+}
+
+func (g *Graph) assignScopes() {
+	for _, node := range g.Nodes {
+		node.Scope = ""
+	}
+	for _, root := range g.Roots {
+		if node, ok := g.Nodes[root.Key()]; ok && scopeRank[root.Scope] > scopeRank[node.Scope] {
+			node.Scope = root.Scope
+		}
+	}
+
+	// Scopes only ever widen, so this settles after a few passes.
+	for changed := true; changed; {
+		changed = false
+		for _, key := range g.Order {
+			node := g.Nodes[key]
+			if node.Scope == "" {
+				continue
+			}
+			for _, dep := range node.Dependencies {
+				child, ok := g.Nodes[dep.Key()]
+				if !ok {
+					continue
+				}
+				scope := transitiveScope(node.Scope, dep.Scope)
+				if scopeRank[scope] > scopeRank[child.Scope] {
+					child.Scope = scope
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+// resolveGraph resolves the full dependency graph of roots using the given
+// conflict strategy, reading poms from the given repositories. Roots built
+// from source take their dependencies from sources.
+func resolveGraph(roots []Artifact, sources []*sourceBuild, strategy string, overrides map[string]string, repositories []config.Repository) (*Graph, error) {
+	r := &graphResolver{
+		poms:      &pom.Resolver{Fetch: pomFetcher(repositories)},
+		sources:   map[string][]Artifact{},
+		overrides: overrides,
+	}
+	for _, source := range sources {
+		if !source.Nested {
+			roots = append(roots, source.Artifact)
+		}
+		r.sources[source.Key()] = source.Dependencies
+	}
+	return r.resolve(roots, strategy)
+}
+
+// resolve walks the graph of roots until the versions selected by the
+// conflict strategy settle.
+func (r *graphResolver) resolve(roots []Artifact, strategy string) (*Graph, error) {
+	if strategy != config.StrategyHighest {
+		graph, _, err := r.walk(roots, nil)
+		if err != nil {
+			return nil, err
+		}
+		graph.Strategy = config.StrategyNearest
+		return graph, nil
+	}
+
+	// Picking a higher version changes which poms are visited, so keep
+	// walking until the selection settles.
+	pinned := map[string]string{}
+	for i := 0; i < 20; i++ {
+		graph, requested, err := r.walk(roots, pinned)
+		if err != nil {
+			return nil, err
+		}
+
+		next := map[string]string{}
+		for key, versions := range requested {
+			highest := versions[0]
+			for _, version := range versions[1:] {
+				if pom.CompareVersions(version, highest) > 0 {
+					highest = version
+				}
+			}
+			next[key] = highest
+		}
+
+		if samePins(pinned, next) {
+			graph.Strategy = config.StrategyHighest
+			return graph, nil
+		}
+		pinned = next
+	}
+	return nil, fmt.Errorf("dependency versions did not settle using the %s strategy", strategy)
+}
+
+func samePins(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, version := range a {
+		if b[key] != version {
+			return false
+		}
+	}
+	return true
+}
+
+// mavenRoots returns the maven dependencies declared in amber.toml.
+func mavenRoots(cfg *config.Config) ([]Artifact, error) {
+	var roots []Artifact
+	for _, name := range cfg.DependencyNames() {
+		dep := cfg.Dependencies[name]
+		if dep.Origin != "maven" {
+			continue
+		}
+		groupID, artifactID, err := config.SplitMavenName(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := config.ValidateScope(dep.Scope); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, excluded := range dep.Exclude {
+			if err := config.ValidateCoordinate(excluded, true); err != nil {
+				return nil, fmt.Errorf("%s: exclude: %w", name, err)
+			}
+		}
+		roots = append(roots, Artifact{
+			GroupID:    groupID,
+			ArtifactID: artifactID,
+			Version:    dep.Version,
+			Classifier: dep.Classifier,
+			Type:       dep.Type,
+			Scope:      dep.EffectiveScope(),
+			Exclusions: dep.Exclude,
+		})
+	}
+	return roots, nil
+}
+
+// ResolveProject resolves the maven dependencies declared in amber.toml
+// together with the projects it builds from source.
+func ResolveProject() (*Graph, error) {
+	cfg, err := config.GetTomlConfig()
+	if err != nil {
+		return nil, err
+	}
+	roots, err := mavenRoots(cfg)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := lock.Read()
+	if err != nil {
+		return nil, err
+	}
+	sources, err := fetchSources(cfg, previous)
+	if err != nil {
+		return nil, err
+	}
+	defer removeSources(sources)
+	return resolveProject(cfg, roots, sources)
+}
+
+// resolveProject resolves roots and the dependencies of the sources using
+// the strategy and repositories of cfg.
+func resolveProject(cfg *config.Config, roots []Artifact, sources []*sourceBuild) (*Graph, error) {
+	strategy, err := cfg.ConflictStrategy()
+	if err != nil {
+		return nil, err
+	}
+	repositories, err := projectRepositories(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		repositories = mergeRepositories(repositories, source.Repositories)
+	}
+
+	overrides, err := cfg.VersionOverrides()
+	if err != nil {
+		return nil, err
+	}
+	graph, err := resolveGraph(roots, sources, strategy, overrides, repositories)
+	if err != nil {
+		return nil, err
+	}
+	graph.Repositories = repositories
+	return graph, nil
+}
+
+// ProjectGraph returns the dependency graph of the project as recorded in
+// the lock file, without resolving or downloading anything. Only a project
+// that has no lock file yet is resolved, which works offline as long as
+// every pom is cached. It also returns how amber.toml drifted from the
+// lock file.
+func ProjectGraph() (*Graph, []string, error) {
+	cfg, err := config.GetTomlConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err := lock.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(l.Artifacts) > 0 {
+		return lockedGraph(cfg, l), l.Drift(cfg), nil
+	}
+
+	graph, err := ResolveProject()
+	if err != nil {
+		return nil, nil, err
+	}
+	// GitHub releases are installed as they are, not resolved
+	for _, name := range cfg.DependencyNames() {
+		dep := cfg.Dependencies[name]
+		user, repo, ok := strings.Cut(name, "/")
+		if dep.Origin != "github" || dep.Build || !ok {
+			continue
+		}
+		version := dep.Tag
+		if version == "" {
+			version = "latest"
+		}
+		root := Artifact{GroupID: user, ArtifactID: repo, Version: version, Scope: dep.EffectiveScope()}
+		graph.Roots = append(graph.Roots, root)
+		graph.Nodes[root.Key()] = &Node{Artifact: root}
+		graph.Order = append(graph.Order, root.Key())
+	}
+	return graph, nil, nil
+}
+
+// lockedArtifact turns an artifact of the lock file into a graph artifact.
+func lockedArtifact(a *lock.Artifact) Artifact {
+	return Artifact{
+		GroupID:    a.GroupID,
+		ArtifactID: a.ArtifactID,
+		Version:    a.Version,
+		Classifier: a.Classifier,
+		Type:       a.Type,
+		Scope:      a.Scope,
+	}
+}
+
+// edgeArtifact parses a groupId:artifactId[:classifier]:version edge of
+// the lock file.
+func edgeArtifact(edge string) Artifact {
+	parts := strings.Split(edge, ":")
+	a := Artifact{GroupID: parts[0]}
+	if len(parts) > 1 {
+		a.ArtifactID = parts[1]
+	}
+	if len(parts) > 2 {
+		a.Version = parts[len(parts)-1]
+	}
+	if len(parts) > 3 {
+		a.Classifier = parts[2]
+	}
+	return a
+}
+
+// lockedGraph rebuilds the graph the lock file was written from. Scopes,
+// edges and exclusions are the recorded ones and depths follow the edges
+// from the dependencies declared in amber.toml.
+func lockedGraph(cfg *config.Config, l *lock.Lock) *Graph {
+	graph := &Graph{Nodes: map[string]*Node{}, Strategy: l.Strategy}
+	nodes := map[string]*Node{}
+	excluded := map[*Node][]string{}
+	for lockKey, a := range l.Artifacts {
+		node := &Node{Artifact: lockedArtifact(a), Depth: -1}
+		if a.Built {
+			node.Packaging = packagingSource
+		}
+		if version, ok := l.Overrides[a.GroupID+":"+a.ArtifactID]; ok && a.Origin == "maven" && version == a.Version {
+			node.Overridden = true
+		}
+		for _, edge := range a.Dependencies {
+			node.Dependencies = append(node.Dependencies, edgeArtifact(edge))
+		}
+		nodes[lockKey] = node
+		excluded[node] = a.Excluded
+	}
+
+	var queue []*Node
+	visit := func(node *Node, depth int) {
+		if node.Depth >= 0 {
+			return
+		}
+		node.Depth = depth
+		graph.Nodes[node.Key()] = node
+		graph.Order = append(graph.Order, node.Key())
+		queue = append(queue, node)
+	}
+	for _, name := range cfg.DependencyNames() {
+		dep := cfg.Dependencies[name]
+		node, ok := nodes[lock.RootKey(name, dep)]
+		if !ok {
+			continue
+		}
+		root := node.Artifact
+		root.Scope = dep.EffectiveScope()
+		graph.Roots = append(graph.Roots, root)
+		visit(node, 0)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, edge := range excluded[node] {
+			graph.Excluded = append(graph.Excluded, Excluded{Artifact: edgeArtifact(edge), From: node.Artifact})
+		}
+		for _, dep := range node.Dependencies {
+			child, ok := nodes[dep.Key()]
+			if !ok {
+				// boms and other pom packaged artifacts have no jar to lock
+				child = &Node{Artifact: dep, Packaging: "pom", Depth: -1}
+				child.Scope = node.Scope
+				nodes[dep.Key()] = child
+			}
+			visit(child, node.Depth+1)
+		}
+	}
+	return graph
+}
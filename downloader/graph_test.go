@@ -0,0 +1,386 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"jpkg/pkg/config"
+	"jpkg/pkg/lock"
+	"jpkg/pom"
+	"strings"
+	"testing"
+)
+
+// testRepository holds poms keyed by group:artifact:version, each listing
+// its dependencies as group:artifact:version[:scope], optionally followed
+// by !group:artifact,... exclusions.
+type testRepository map[string][]string
+
+// testArtifact parses a dependency given as in testRepository.
+func testArtifact(dep string) Artifact {
+	dep, exclusions, _ := strings.Cut(dep, "!")
+	parts := strings.Split(dep, ":")
+	a := Artifact{GroupID: parts[0], ArtifactID: parts[1], Version: parts[2], Scope: config.ScopeCompile}
+	if len(parts) > 3 {
+		a.Scope = parts[3]
+	}
+	if exclusions != "" {
+		a.Exclusions = strings.Split(exclusions, ",")
+	}
+	return a
+}
+
+func (repo testRepository) fetch(groupID, artifactID, version string) (io.ReadCloser, error) {
+	deps, ok := repo[groupID+":"+artifactID+":"+version]
+	if !ok {
+		return nil, fmt.Errorf("%s:%s:%s not found", groupID, artifactID, version)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<project><groupId>%s</groupId><artifactId>%s</artifactId><version>%s</version><dependencies>", groupID, artifactID, version)
+	for _, dep := range deps {
+		a := testArtifact(dep)
+		fmt.Fprintf(&b, "<dependency><groupId>%s</groupId><artifactId>%s</artifactId><version>%s</version><scope>%s</scope><exclusions>", a.GroupID, a.ArtifactID, a.Version, a.Scope)
+		for _, excluded := range a.Exclusions {
+			groupID, artifactID, _ := strings.Cut(excluded, ":")
+			fmt.Fprintf(&b, "<exclusion><groupId>%s</groupId><artifactId>%s</artifactId></exclusion>", groupID, artifactID)
+		}
+		b.WriteString("</exclusions></dependency>")
+	}
+	b.WriteString("</dependencies></project>")
+	return io.NopCloser(strings.NewReader(b.String())), nil
+}
+
+func (repo testRepository) resolve(t *testing.T, strategy string, overrides map[string]string, roots ...string) *Graph {
+	t.Helper()
+	var artifacts []Artifact
+	for _, root := range roots {
+		artifacts = append(artifacts, testArtifact(root))
+	}
+	r := &graphResolver{
+		poms:      &pom.Resolver{Fetch: repo.fetch},
+		sources:   map[string][]Artifact{},
+		overrides: overrides,
+	}
+	graph, err := r.resolve(artifacts, strategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return graph
+}
+
+// selected returns the versions chosen for every artifact of the graph.
+func selected(graph *Graph) map[string]string {
+	versions := map[string]string{}
+	for key, node := range graph.Nodes {
+		versions[key] = node.Version
+	}
+	return versions
+}
+
+func checkSelected(t *testing.T, graph *Graph, want map[string]string) {
+	t.Helper()
+	got := selected(graph)
+	if len(got) != len(want) {
+		t.Errorf("selected %v, want %v", got, want)
+		return
+	}
+	for key, version := range want {
+		if got[key] != version {
+			t.Errorf("%s = %q, want %q", key, got[key], version)
+		}
+	}
+}
+
+func TestNearestWins(t *testing.T) {
+	repo := testRepository{
+		"g:a:1": {"g:b:1", "g:c:1"},
+		"g:b:1": {"g:c:2", "g:d:1"},
+		"g:c:1": nil,
+		"g:c:2": nil,
+		"g:d:1": {"g:e:1"},
+		"g:e:1": nil,
+		"g:e:2": nil,
+		"g:x:1": {"g:e:2"},
+		"g:y:1": {"g:e:1"},
+		"g:w:1": {"g:d:1!g:e"},
+	}
+
+	tests := []struct {
+		name      string
+		roots     []string
+		overrides map[string]string
+		want      map[string]string
+		// excluded lists the excluded dependencies as from -> artifact
+		excluded []string
+	}{
+		{
+			name:  "nearer request wins over a higher version",
+			roots: []string{"g:a:1"},
+			want:  map[string]string{"g:a": "1", "g:b": "1", "g:c": "1", "g:d": "1", "g:e": "1"},
+		},
+		{
+			name:  "declared root wins",
+			roots: []string{"g:b:1", "g:c:1"},
+			want:  map[string]string{"g:b": "1", "g:c": "1", "g:d": "1", "g:e": "1"},
+		},
+		{
+			name:  "first declaration wins a tie",
+			roots: []string{"g:x:1", "g:y:1"},
+			want:  map[string]string{"g:x": "1", "g:y": "1", "g:e": "2"},
+		},
+		{
+			name:  "first declaration wins a tie in the other order",
+			roots: []string{"g:y:1", "g:x:1"},
+			want:  map[string]string{"g:x": "1", "g:y": "1", "g:e": "1"},
+		},
+		{
+			name:      "overrides win over the nearest request",
+			roots:     []string{"g:a:1"},
+			overrides: map[string]string{"g:c": "2"},
+			want:      map[string]string{"g:a": "1", "g:b": "1", "g:c": "2", "g:d": "1", "g:e": "1"},
+		},
+		{
+			name:     "exclusions of a root apply to the whole path",
+			roots:    []string{"g:a:1!g:e"},
+			want:     map[string]string{"g:a": "1", "g:b": "1", "g:c": "1", "g:d": "1"},
+			excluded: []string{"g:d:1 -> g:e:1"},
+		},
+		{
+			name:     "wildcard group",
+			roots:    []string{"g:a:1!*:d"},
+			want:     map[string]string{"g:a": "1", "g:b": "1", "g:c": "1"},
+			excluded: []string{"g:b:1 -> g:d:1"},
+		},
+		{
+			name:     "wildcard artifact",
+			roots:    []string{"g:a:1!g:*"},
+			want:     map[string]string{"g:a": "1"},
+			excluded: []string{"g:a:1 -> g:b:1", "g:a:1 -> g:c:1"},
+		},
+		{
+			name:     "exclusions of a pom",
+			roots:    []string{"g:w:1"},
+			want:     map[string]string{"g:w": "1", "g:d": "1"},
+			excluded: []string{"g:d:1 -> g:e:1"},
+		},
+		{
+			name:     "excluded on one path only",
+			roots:    []string{"g:x:1!g:e", "g:y:1"},
+			want:     map[string]string{"g:x": "1", "g:y": "1", "g:e": "1"},
+			excluded: []string{"g:x:1 -> g:e:2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := repo.resolve(t, config.StrategyNearest, tt.overrides, tt.roots...)
+			checkSelected(t, graph, tt.want)
+			var excluded []string
+			for _, dep := range graph.Excluded {
+				excluded = append(excluded, dep.From.String()+" -> "+dep.Artifact.String())
+			}
+			if strings.Join(excluded, ", ") != strings.Join(tt.excluded, ", ") {
+				t.Errorf("excluded %v, want %v", excluded, tt.excluded)
+			}
+			if graph.Strategy != config.StrategyNearest {
+				t.Errorf("strategy = %s", graph.Strategy)
+			}
+		})
+	}
+}
+
+func TestHighestWins(t *testing.T) {
+	repo := testRepository{
+		"g:a:1": {"g:b:1", "g:c:1"},
+		"g:b:1": {"g:c:2"},
+		// Only the higher version of c brings in d, and d asks for an
+		// even higher c
+		"g:c:1": nil,
+		"g:c:2": {"g:d:1"},
+		"g:c:3": {"g:d:1"},
+		"g:d:1": {"g:c:3"},
+		"g:x:1": {"g:e:2"},
+		"g:y:1": {"g:e:1"},
+		"g:e:1": nil,
+		"g:e:2": nil,
+	}
+
+	tests := []struct {
+		name      string
+		roots     []string
+		overrides map[string]string
+		want      map[string]string
+	}{
+		{
+			name:  "highest request wins and pulls in its dependencies",
+			roots: []string{"g:a:1"},
+			want:  map[string]string{"g:a": "1", "g:b": "1", "g:c": "3", "g:d": "1"},
+		},
+		{
+			name:  "highest wins a tie whatever the order",
+			roots: []string{"g:y:1", "g:x:1"},
+			want:  map[string]string{"g:x": "1", "g:y": "1", "g:e": "2"},
+		},
+		{
+			name:      "overrides win over the highest request",
+			roots:     []string{"g:a:1"},
+			overrides: map[string]string{"g:c": "1"},
+			want:      map[string]string{"g:a": "1", "g:b": "1", "g:c": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := repo.resolve(t, config.StrategyHighest, tt.overrides, tt.roots...)
+			checkSelected(t, graph, tt.want)
+			if graph.Strategy != config.StrategyHighest {
+				t.Errorf("strategy = %s", graph.Strategy)
+			}
+		})
+	}
+}
+
+func TestTransitiveScope(t *testing.T) {
+	tests := []struct {
+		parent, declared, want string
+	}{
+		{config.ScopeCompile, config.ScopeCompile, config.ScopeCompile},
+		{config.ScopeCompile, config.ScopeRuntime, config.ScopeRuntime},
+		{config.ScopeRuntime, config.ScopeCompile, config.ScopeRuntime},
+		{config.ScopeRuntime, config.ScopeRuntime, config.ScopeRuntime},
+		{config.ScopeProvided, config.ScopeCompile, config.ScopeProvided},
+		{config.ScopeProvided, config.ScopeRuntime, config.ScopeProvided},
+		{config.ScopeTest, config.ScopeCompile, config.ScopeTest},
+		{config.ScopeTest, config.ScopeRuntime, config.ScopeTest},
+	}
+	for _, tt := range tests {
+		if got := transitiveScope(tt.parent, tt.declared); got != tt.want {
+			t.Errorf("transitiveScope(%s, %s) = %s, want %s", tt.parent, tt.declared, got, tt.want)
+		}
+	}
+}
+
+func TestAssignScopes(t *testing.T) {
+	repo := testRepository{
+		"g:app:1":      {"g:lib:1", "g:driver:1:runtime"},
+		"g:lib:1":      {"g:util:1"},
+		"g:driver:1":   {"g:pool:1"},
+		"g:util:1":     nil,
+		"g:pool:1":     nil,
+		"g:junit:1":    {"g:util:1", "g:hamcrest:1"},
+		"g:api:1":      {"g:pool:1"},
+		"g:hamcrest:1": nil,
+	}
+
+	graph := repo.resolve(t, config.StrategyNearest, nil, "g:app:1", "g:junit:1:test", "g:api:1:provided")
+	want := map[string]string{
+		"g:app":    config.ScopeCompile,
+		"g:lib":    config.ScopeCompile,
+		"g:driver": config.ScopeRuntime,
+		// reached through both a test and a compile dependency
+		"g:util": config.ScopeCompile,
+		// reached through both a provided and a runtime dependency
+		"g:pool":     config.ScopeRuntime,
+		"g:junit":    config.ScopeTest,
+		"g:hamcrest": config.ScopeTest,
+		"g:api":      config.ScopeProvided,
+	}
+	for key, scope := range want {
+		node, ok := graph.Nodes[key]
+		if !ok {
+			t.Errorf("%s is missing", key)
+			continue
+		}
+		if node.Scope != scope {
+			t.Errorf("%s scope = %s, want %s", key, node.Scope, scope)
+		}
+	}
+}
+
+// lockGraph records graph the way jpkg install writes it to the lock file.
+func lockGraph(t *testing.T, graph *Graph) *lock.Lock {
+	t.Helper()
+	l := lock.New()
+	l.Strategy = graph.Strategy
+	for _, key := range graph.Order {
+		node := graph.Nodes[key]
+		entry := &lock.Artifact{
+			GroupID:    node.GroupID,
+			ArtifactID: node.ArtifactID,
+			Version:    node.Version,
+			Origin:     "maven",
+			Scope:      node.Scope,
+			File:       node.libFile(),
+		}
+		entry.Dependencies, entry.Excluded = graph.lockEdges(node)
+		l.Artifacts[entry.Key()] = entry
+	}
+
+	content, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := lock.New()
+	if err := json.Unmarshal(content, read); err != nil {
+		t.Fatal(err)
+	}
+	return read
+}
+
+func TestLockedGraphExclusions(t *testing.T) {
+	repo := testRepository{
+		"g:app:1":     {"g:lib:1!g:logging"},
+		"g:lib:1":     {"g:logging:1", "g:util:1"},
+		"g:util:1":    {"g:logging:1"},
+		"g:logging:1": nil,
+	}
+	resolved := repo.resolve(t, config.StrategyNearest, nil, "g:app:1")
+
+	cfg := &config.Config{}
+	cfg.AddDependency("g/app", config.Dependency{Origin: "maven", Version: "1"})
+	graph := lockedGraph(cfg, lockGraph(t, resolved))
+
+	if _, ok := graph.Selected("g:logging"); ok {
+		t.Error("g:logging was selected")
+	}
+	if len(graph.Excluded) != len(resolved.Excluded) {
+		t.Fatalf("excluded %v, want %v", graph.Excluded, resolved.Excluded)
+	}
+
+	// What jpkg why logging prints
+	var why []string
+	for _, dep := range graph.FindExcluded("logging") {
+		for _, path := range graph.Paths(dep.From.Key()) {
+			var names []string
+			for _, a := range path {
+				names = append(names, a.String())
+			}
+			why = append(why, strings.Join(names, " -> ")+" -> "+dep.Artifact.String())
+		}
+	}
+	want := []string{
+		"g:app:1 -> g:lib:1 -> g:logging:1",
+		"g:app:1 -> g:lib:1 -> g:util:1 -> g:logging:1",
+	}
+	if strings.Join(why, "\n") != strings.Join(want, "\n") {
+		t.Errorf("why logging\n%s\nwant\n%s", strings.Join(why, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestUnresolvedPOM(t *testing.T) {
+	repo := testRepository{
+		"g:a:1": {"g:b:1", "g:c:1"},
+		"g:b:1": {"g:missing:1"},
+		"g:c:1": {"g:gone:2"},
+	}
+	r := &graphResolver{poms: &pom.Resolver{Fetch: repo.fetch}, sources: map[string][]Artifact{}}
+	_, err := r.resolve([]Artifact{testArtifact("g:a:1")}, config.StrategyNearest)
+	if err == nil {
+		t.Fatal("resolved a graph with missing poms")
+	}
+	// Every missing pom is reported at once
+	for _, artifact := range []string{"g:missing:1", "g:gone:2"} {
+		if !strings.Contains(err.Error(), artifact) {
+			t.Errorf("%s is not reported in %v", artifact, err)
+		}
+	}
+}
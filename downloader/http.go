@@ -0,0 +1,176 @@
+package downloader
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"jpkg/pkg/config"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// httpError is a response with an unexpected status.
+type httpError struct {
+	URL    string
+	Status string
+	Code   int
+	// Upload tells that the request was a PUT of jpkg publish.
+	Upload bool
+}
+
+func (e *httpError) Error() string {
+	action := "fetch"
+	if e.Upload {
+		action = "upload"
+	}
+	if e.Code == http.StatusUnauthorized || e.Code == http.StatusForbidden {
+		return fmt.Sprintf("failed to %s %s: %s (check the credentials in settings.toml)", action, e.URL, e.Status)
+	}
+	return fmt.Sprintf("failed to %s %s: %s", action, e.URL, e.Status)
+}
+
+var errStalled = errors.New("timed out waiting for data")
+
+// httpClient makes every request of jpkg. Its transport is set up from
+// the user settings on first use.
+var httpClient = &http.Client{}
+
+// userSettings loads the user settings once per run.
+var userSettings = sync.OnceValues(config.GetSettings)
+
+var setupClient = sync.OnceValue(configureClient)
+
+// configureClient gives httpClient the proxy and TLS settings of the user,
+// unless it already has a transport.
+func configureClient() error {
+	if httpClient.Transport != nil {
+		return nil
+	}
+	settings, err := userSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	transport, err := newTransport(settings.HTTP)
+	if err != nil {
+		return fmt.Errorf("invalid [http] settings: %w", err)
+	}
+	httpClient.Transport = transport
+	return nil
+}
+
+// httpGet requests url, asking for the content from offset on when offset
+// is positive. The request is cancelled when the response, or the next
+// bytes of its body, take longer than the configured timeout.
+func httpGet(url string, offset int64) (*http.Response, error) {
+	if offline() {
+		return nil, fmt.Errorf("%w, cannot fetch %s", errOffline, url)
+	}
+	settings, err := userSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	timeout, err := settings.DownloadTimeout()
+	if err != nil {
+		return nil, err
+	}
+	if err := setupClient(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body := &stallReader{timeout: timeout, cancel: cancel}
+	body.timer = time.AfterFunc(timeout, body.stall)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err == nil {
+		err = authorize(req)
+	}
+	if err == nil {
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		var resp *http.Response
+		if resp, err = httpClient.Do(req); err == nil {
+			body.body = resp.Body
+			body.timer.Reset(timeout)
+			resp.Body = body
+			return resp, nil
+		}
+	}
+
+	body.timer.Stop()
+	cancel()
+	if body.stalled.Load() {
+		return nil, fmt.Errorf("%s: %w", url, errStalled)
+	}
+	return nil, err
+}
+
+// stallReader cancels its request once no data arrived for the timeout.
+type stallReader struct {
+	body    io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	cancel  context.CancelFunc
+	stalled atomic.Bool
+}
+
+func (r *stallReader) stall() {
+	r.stalled.Store(true)
+	r.cancel()
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if err != nil && r.stalled.Load() {
+		return n, errStalled
+	}
+	r.timer.Reset(r.timeout)
+	return n, err
+}
+
+func (r *stallReader) Close() error {
+	r.timer.Stop()
+	r.cancel()
+	return r.body.Close()
+}
+
+// retryable reports whether a failed download may succeed when it is
+// tried again. Timeouts, dropped connections and server errors are
+// temporary, while certificate problems and unknown hosts are not.
+func retryable(err error) bool {
+	var statusErr *httpError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= 500 || statusErr.Code == http.StatusRequestTimeout || statusErr.Code == http.StatusTooManyRequests
+	}
+
+	var certErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var dnsErr *net.DNSError
+	if errors.As(err, &certErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return false
+	}
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+
+	if errors.Is(err, errStalled) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	// Every error of http.Client.Do is a net.Error, only its timeouts are
+	// worth another try
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,57 @@
+package downloader
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// doError wraps err the way http.Client.Do does.
+func doError(err error) error {
+	return &url.Error{Op: "Get", URL: "https://repo.example.com/a.jar", Err: err}
+}
+
+func dialError(err error) error {
+	return doError(&net.OpError{Op: "dial", Net: "tcp", Err: err})
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &httpError{Code: 503}, true},
+		{"request timeout", &httpError{Code: 408}, true},
+		{"too many requests", &httpError{Code: 429}, true},
+		{"not found", &httpError{Code: 404}, false},
+		{"unauthorized", &httpError{Code: 401}, false},
+		{"stalled", fmt.Errorf("https://repo.example.com/a.jar: %w", errStalled), true},
+		{"truncated body", io.ErrUnexpectedEOF, true},
+		{"timeout", doError(context.DeadlineExceeded), true},
+		{"connection reset", dialError(os.NewSyscallError("read", syscall.ECONNRESET)), true},
+		{"connection refused", dialError(os.NewSyscallError("connect", syscall.ECONNREFUSED)), true},
+		{"dns timeout", dialError(&net.DNSError{Err: "i/o timeout", Name: "repo.example.com", IsTimeout: true}), true},
+		{"unknown host", dialError(&net.DNSError{Err: "no such host", Name: "repo.example.com", IsNotFound: true}), false},
+		{"unknown authority", doError(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}), false},
+		{"wrong host name", doError(x509.HostnameError{Host: "repo.example.com", Certificate: &x509.Certificate{}}), false},
+		{"expired certificate", doError(x509.CertificateInvalidError{Reason: x509.Expired}), false},
+		{"unsupported scheme", doError(errors.New("unsupported protocol scheme")), false},
+		{"other", errors.New("disk full"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"jpkg/pkg/config"
+	"jpkg/pom"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Imported is a project translated from a Maven or Gradle build, together
+// with everything that could not be translated.
+type Imported struct {
+	Config  *config.Config
+	Skipped []string
+}
+
+func (i *Imported) skip(format string, args ...any) {
+	i.Skipped = append(i.Skipped, fmt.Sprintf(format, args...))
+}
+
+// addDependency declares a maven dependency from its coordinates, naming
+// it group/artifact with the classifier appended like jpkg install does.
+func (i *Imported) addDependency(groupID, artifactID string, dep config.Dependency) {
+	name := groupID + "/" + artifactID
+	if dep.Classifier != "" {
+		name += ":" + dep.Classifier
+	}
+	if dep.Type == "jar" {
+		dep.Type = ""
+	}
+	if dep.Scope == config.ScopeCompile {
+		dep.Scope = ""
+	}
+	if _, ok := i.Config.Dependencies[name]; ok {
+		i.skip("%s is declared more than once, only the first one is kept", name)
+		return
+	}
+	i.Config.AddDependency(name, dep)
+}
+
+// javaRelease turns a Java version such as 1.8 into the number javac
+// --release takes.
+func javaRelease(version string) string {
+	return strings.TrimPrefix(strings.TrimSpace(version), "1.")
+}
+
+// translatedPlugins are the Maven plugins whose settings end up in
+// amber.toml, or that need none.
+var translatedPlugins = map[string]bool{
+	"maven-compiler-plugin":  true,
+	"maven-jar-plugin":       true,
+	"maven-resources-plugin": true,
+	"exec-maven-plugin":      true,
+}
+
+func parsePOMFile(path string) (*pom.Project, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	project, err := pom.Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return project, nil
+}
+
+// pomCoordinates returns the groupId and version of a raw pom, which may
+// inherit them from its parent.
+func pomCoordinates(project *pom.Project) (string, string) {
+	groupID, version := project.GroupID, project.Version
+	if project.Parent != nil {
+		if groupID == "" {
+			groupID = project.Parent.GroupID
+		}
+		if version == "" {
+			version = project.Parent.Version
+		}
+	}
+	return groupID, version
+}
+
+// parentPOMPath returns where the parent of the pom at path lives in the
+// checkout, or "" when it is only available from repositories.
+func parentPOMPath(path string, parent *pom.Parent) string {
+	relative := "../pom.xml"
+	if parent.RelativePath != nil {
+		relative = strings.TrimSpace(*parent.RelativePath)
+	}
+	if relative == "" {
+		return ""
+	}
+	parentPath := filepath.Join(filepath.Dir(path), filepath.FromSlash(relative))
+	if info, err := os.Stat(parentPath); err == nil && info.IsDir() {
+		parentPath = filepath.Join(parentPath, "pom.xml")
+	}
+	return parentPath
+}
+
+// trimBasedir makes a path declared in a pom relative to its directory.
+func trimBasedir(path string) string {
+	for _, prefix := range []string{"${project.basedir}/", "${basedir}/"} {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	return filepath.ToSlash(filepath.Clean(strings.TrimSpace(path)))
+}
+
+// ImportMaven translates the pom.xml at path. Parents are read from the
+// checkout when their relativePath points at them and from the
+// repositories otherwise, and properties are resolved like Maven does.
+func ImportMaven(path string) (*Imported, error) {
+	root, err := parsePOMFile(path)
+	if err != nil {
+		return nil, err
+	}
+	groupID, version := pomCoordinates(root)
+
+	// The poms of the checkout, the project first and then its parents,
+	// keyed by the coordinates the resolver asks for
+	locals := []*pom.Project{root}
+	localPaths := map[string]string{groupID + ":" + root.ArtifactID + ":" + version: path}
+	remoteParent := ""
+	for current, currentPath := root, path; current.Parent != nil; {
+		parent := current.Parent
+		key := parent.GroupID + ":" + parent.ArtifactID + ":" + parent.Version
+		parentPath := parentPOMPath(currentPath, parent)
+		var project *pom.Project
+		if parentPath != "" {
+			project, _ = parsePOMFile(parentPath)
+		}
+		if project == nil {
+			remoteParent = key
+			break
+		}
+		if parentGroupID, parentVersion := pomCoordinates(project); parentGroupID != parent.GroupID || project.ArtifactID != parent.ArtifactID || parentVersion != parent.Version {
+			remoteParent = key
+			break
+		}
+		locals = append(locals, project)
+		localPaths[key] = parentPath
+		current, currentPath = project, parentPath
+	}
+
+	// Repository urls may use the properties of the checkout
+	properties := pom.Properties{}
+	for i := len(locals) - 1; i >= 0; i-- {
+		for name, value := range locals[i].Properties {
+			properties[name] = value
+		}
+	}
+	cfg := &config.Config{}
+	imported := &Imported{Config: cfg}
+	for _, project := range locals {
+		for _, repository := range project.Repositories {
+			id, url := strings.TrimSpace(repository.ID), strings.TrimSuffix(properties.Expand(strings.TrimSpace(repository.URL)), "/")
+			if _, ok := cfg.Repositories[id]; ok || url == config.CentralURL {
+				continue
+			}
+			if strings.Contains(url, "${") {
+				imported.skip("repository %s has an unresolved url %s", id, url)
+				continue
+			}
+			cfg.AddRepository(id, url)
+		}
+	}
+
+	repositories, err := projectRepositories(cfg)
+	if err != nil {
+		return nil, err
+	}
+	fetch := pomFetcher(repositories)
+	resolver := &pom.Resolver{Fetch: func(groupID, artifactID, version string) (io.ReadCloser, error) {
+		if localPath, ok := localPaths[groupID+":"+artifactID+":"+version]; ok {
+			return os.Open(localPath)
+		}
+		return fetch(groupID, artifactID, version)
+	}}
+	project, err := resolver.Effective(groupID, root.ArtifactID, version)
+	if err != nil {
+		return nil, err
+	}
+	properties = project.Properties
+
+	if !strings.Contains(project.GroupID+project.Version, "${") {
+		cfg.Group, cfg.Name, cfg.Version = project.GroupID, project.ArtifactID, project.Version
+	}
+	switch packaging := strings.TrimSpace(root.Packaging); packaging {
+	case "", "jar", "bundle":
+	case "pom":
+		if len(root.Modules) > 0 {
+			imported.skip("modules %s, run jpkg import in each of them", strings.Join(root.Modules, ", "))
+		}
+	default:
+		imported.skip("packaging %s, jpkg builds jars", packaging)
+	}
+
+	srcDir := "src/main/java"
+	if dir := trimBasedir(properties.Expand(root.Build.SourceDirectory)); dir != "." {
+		srcDir = dir
+	}
+	if srcDir != "src" {
+		cfg.SrcDir = srcDir
+	}
+	resourcesDir := filepath.ToSlash(filepath.Join(filepath.Dir(srcDir), "resources"))
+	for _, resource := range root.Build.Resources {
+		if dir := trimBasedir(properties.Expand(resource.Directory)); dir != resourcesDir {
+			imported.skip("resources in %s, jpkg only reads %s", dir, resourcesDir)
+		}
+	}
+	testDir := "src/test/java"
+	if dir := trimBasedir(properties.Expand(root.Build.TestSourceDirectory)); dir != "." {
+		testDir = dir
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), testDir)); err == nil {
+		imported.skip("test sources in %s, jpkg does not compile tests", testDir)
+	}
+
+	for _, name := range []string{"maven.compiler.release", "maven.compiler.target", "maven.compiler.source"} {
+		if value := properties.Expand(properties[name]); value != "" && cfg.Release == "" {
+			cfg.Release = javaRelease(value)
+		}
+	}
+	for _, name := range []string{"exec.mainClass", "main.class", "mainClass"} {
+		if value := properties.Expand(properties[name]); value != "" && cfg.MainClass == "" {
+			cfg.MainClass = value
+		}
+	}
+	for _, plugin := range root.Build.Plugins {
+		artifactID := strings.TrimSpace(plugin.ArtifactID)
+		if !translatedPlugins[artifactID] {
+			imported.skip("plugin %s", artifactID)
+			continue
+		}
+		configuration := plugin.Configuration
+		for _, value := range []string{configuration.ManifestMainClass, configuration.MainClass} {
+			if value = strings.TrimSpace(properties.Expand(value)); value != "" && cfg.MainClass == "" {
+				cfg.MainClass = value
+			}
+		}
+		for _, value := range []string{configuration.Release, configuration.Target, configuration.Source} {
+			if value = strings.TrimSpace(properties.Expand(value)); value != "" && cfg.Release == "" {
+				cfg.Release = javaRelease(value)
+			}
+		}
+	}
+	if strings.Contains(cfg.Release, "${") {
+		imported.skip("compiler release %s", cfg.Release)
+		cfg.Release = ""
+	}
+
+	for _, dep := range project.Dependencies {
+		if strings.Contains(dep.String()+dep.Classifier+dep.Type, "${") || dep.Version == "" {
+			imported.skip("dependency %s has no resolvable version", dep)
+			continue
+		}
+		scope := dep.Scope
+		if scope == "" {
+			scope = config.ScopeCompile
+		}
+		if err := config.ValidateScope(scope); err != nil {
+			imported.skip("dependency %s has the %s scope", dep, scope)
+			continue
+		}
+		imported.addDependency(dep.GroupID, dep.ArtifactID, config.Dependency{
+			Origin:     "maven",
+			Version:    dep.Version,
+			Classifier: dep.Classifier,
+			Type:       dep.Type,
+			Scope:      scope,
+			Exclude:    exclusions(dep.Exclusions),
+		})
+	}
+
+	// Versions managed by the checkout apply to transitive dependencies as
+	// well, which is what [overrides] does. Managed versions of boms and
+	// remote parents already went into the declared dependencies.
+	for _, local := range locals {
+		for _, dep := range local.DependencyManagement {
+			groupID, artifactID, version := properties.Expand(dep.GroupID), properties.Expand(dep.ArtifactID), properties.Expand(dep.Version)
+			if properties.Expand(dep.Scope) == "import" {
+				imported.skip("managed versions of the imported bom %s:%s:%s", groupID, artifactID, version)
+				continue
+			}
+			coordinate := groupID + ":" + artifactID
+			if strings.Contains(coordinate+version, "${") || version == "" {
+				imported.skip("managed version of %s", coordinate)
+				continue
+			}
+			if _, ok := cfg.Overrides[coordinate]; ok {
+				continue
+			}
+			if cfg.Overrides == nil {
+				cfg.Overrides = map[string]string{}
+			}
+			cfg.Overrides[coordinate] = version
+		}
+	}
+	if remoteParent != "" {
+		imported.skip("managed versions of the parent %s", remoteParent)
+	}
+	return imported, nil
+}
@@ -0,0 +1,168 @@
+package downloader
+
+import (
+	"jpkg/pkg/config"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// importedDependency describes a dependency as
+// version[:classifier][ scope][ exclude,exclude].
+func importedDependency(dep config.Dependency) string {
+	description := dep.Version
+	if dep.Classifier != "" {
+		description += ":" + dep.Classifier
+	}
+	if dep.Scope != "" {
+		description += " " + dep.Scope
+	}
+	if len(dep.Exclude) > 0 {
+		description += " " + strings.Join(dep.Exclude, ",")
+	}
+	return description
+}
+
+func checkImported(t *testing.T, imported *Imported, dependencies, overrides map[string]string, skipped []string) {
+	t.Helper()
+	cfg := imported.Config
+	if names := cfg.DependencyNames(); len(names) != len(dependencies) {
+		t.Errorf("dependencies %v, want %v", names, dependencies)
+	}
+	for name, want := range dependencies {
+		dep, ok := cfg.Dependencies[name]
+		if !ok {
+			t.Errorf("%s is missing", name)
+			continue
+		}
+		if dep.Origin != "maven" {
+			t.Errorf("%s origin = %s", name, dep.Origin)
+		}
+		if got := importedDependency(dep); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if len(cfg.Overrides) != len(overrides) {
+		t.Errorf("overrides %v, want %v", cfg.Overrides, overrides)
+	}
+	for coordinate, version := range overrides {
+		if cfg.Overrides[coordinate] != version {
+			t.Errorf("override %s = %q, want %q", coordinate, cfg.Overrides[coordinate], version)
+		}
+	}
+	if strings.Join(imported.Skipped, "\n") != strings.Join(skipped, "\n") {
+		t.Errorf("skipped\n%s\nwant\n%s", strings.Join(imported.Skipped, "\n"), strings.Join(skipped, "\n"))
+	}
+}
+
+func TestImportMaven(t *testing.T) {
+	// The imported bom is fetched from the fixture repository
+	repo, err := filepath.Abs(filepath.Join("testdata", "import", "repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	useSettings(t, "[mirrors]\n\"*\" = \"file://"+filepath.ToSlash(repo)+"\"\n")
+
+	imported, err := ImportMaven(filepath.Join("testdata", "import", "maven", "app", "pom.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := imported.Config
+	if cfg.Group != "org.demo" || cfg.Name != "app" || cfg.Version != "1.2.0" {
+		t.Errorf("coordinates %s:%s:%s", cfg.Group, cfg.Name, cfg.Version)
+	}
+	if cfg.MainClass != "org.demo.Main" || cfg.Release != "17" || cfg.SrcDir != "src/main/java" {
+		t.Errorf("main class %q, release %q, src dir %q", cfg.MainClass, cfg.Release, cfg.SrcDir)
+	}
+	if url := cfg.Repositories["company"]; len(cfg.Repositories) != 1 || url != "https://repo.example.com/maven" {
+		t.Errorf("repositories %v", cfg.Repositories)
+	}
+	checkImported(t, imported, map[string]string{
+		"com.google.guava/guava":        "33.0.0-jre com.google.code.findbugs:jsr305",
+		"com.ex/managed":                "1.0.5",
+		"org.demo/shared:natives-linux": "1.2.0:natives-linux runtime",
+		"junit/junit":                   "4.13.2 test",
+	}, map[string]string{
+		"com.google.guava:guava": "33.0.0-jre",
+		"org.slf4j:slf4j-api":    "2.0.9",
+	}, []string{
+		"plugin maven-shade-plugin",
+		"dependency com.ex:tools:1.0 has the system scope",
+		"managed versions of the imported bom com.ex:bom:1.0",
+	})
+}
+
+func TestImportGradle(t *testing.T) {
+	imported, err := ImportGradle(filepath.Join("testdata", "import", "gradle", "build.gradle.kts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := imported.Config
+	if cfg.Group != "org.demo" || cfg.Name != "demo" || cfg.Version != "0.3" {
+		t.Errorf("coordinates %s:%s:%s", cfg.Group, cfg.Name, cfg.Version)
+	}
+	if cfg.MainClass != "org.demo.Main" || cfg.Release != "21" || cfg.SrcDir != "src/main/java" {
+		t.Errorf("main class %q, release %q, src dir %q", cfg.MainClass, cfg.Release, cfg.SrcDir)
+	}
+	if url := cfg.Repositories["company"]; len(cfg.Repositories) != 1 || url != "https://repo.example.com/releases" {
+		t.Errorf("repositories %v", cfg.Repositories)
+	}
+	checkImported(t, imported, map[string]string{
+		"com.google.guava/guava":                      "33.0.0-jre com.google.code.findbugs:jsr305",
+		"org.demo/natives:natives-linux":              "1.0:natives-linux runtime",
+		"com.fasterxml.jackson.core/jackson-databind": "2.16.1",
+		"junit/junit":                                 "4.13.2 test",
+		"org.hamcrest/hamcrest":                       "2.2 test",
+		"org.projectlombok/lombok":                    "1.18.30 provided",
+	}, map[string]string{
+		"org.slf4j:slf4j-api": "2.0.9",
+	}, []string{
+		"plugin com.github.johnrengelman.shadow",
+		"test sources in src/test/java, jpkg does not compile tests",
+		"catalog libraries commons-lang and commons_lang collide as libs.commons.lang",
+		`dependency implementation(project(":shared"))`,
+		`annotationProcessor dependency "org.projectlombok:lombok:1.18.30"`,
+	})
+}
+
+func TestImportCatalog(t *testing.T) {
+	imported, err := ImportCatalog(filepath.Join("testdata", "import", "gradle", "gradle", "libs.versions.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkImported(t, imported, map[string]string{
+		"com.fasterxml.jackson.core/jackson-databind": "2.16.1",
+		"junit/junit":           "4.13.2",
+		"org.hamcrest/hamcrest": "2.2",
+	}, nil, []string{
+		"catalog libraries commons-lang and commons_lang collide as libs.commons.lang",
+		"catalog library nover has no version",
+		"scopes of the catalog libraries, they are all compile dependencies",
+	})
+}
+
+func TestCatalogCollision(t *testing.T) {
+	catalog := &versionCatalog{
+		Libraries: map[string]any{
+			"commons-lang": "commons-lang:commons-lang:2.6",
+			"commons.lang": "org.apache.commons:commons-lang3:3.14.0",
+			"guava":        "com.google.guava:guava:33.0.0-jre",
+		},
+		Bundles: map[string][]string{
+			"test-libs": {"guava"},
+			"test_libs": {"commons-lang"},
+		},
+	}
+	// Whatever order the map is read in, the collision is reported the same
+	for i := 0; i < 10; i++ {
+		if _, _, _, err := catalog.library("commons.lang"); err == nil || err.Error() != "catalog libraries commons-lang and commons.lang collide as libs.commons.lang" {
+			t.Fatalf("library(commons.lang) error = %v", err)
+		}
+		if _, err := catalog.bundle("test.libs"); err == nil || err.Error() != "catalog bundles test-libs and test_libs collide as libs.bundles.test.libs" {
+			t.Fatalf("bundle(test.libs) error = %v", err)
+		}
+	}
+	if groupID, artifactID, version, err := catalog.library("guava"); err != nil || groupID+":"+artifactID+":"+version != "com.google.guava:guava:33.0.0-jre" {
+		t.Errorf("library(guava) = %s:%s:%s, %v", groupID, artifactID, version, err)
+	}
+}
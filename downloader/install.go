@@ -0,0 +1,565 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"jpkg/pkg/config"
+	"jpkg/pkg/lock"
+	"jpkg/pkg/store"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// installGraph downloads every selected artifact of the graph into libDir
+// and returns their lock entries. Jars that are already installed are
+// checked against the previous lock, and jars of versions that lost a
+// conflict are removed.
+func installGraph(graph *Graph, libDir string, previous *lock.Lock) (map[string]*lock.Artifact, error) {
+	if _, err := os.Stat(libDir); os.IsNotExist(err) {
+		os.Mkdir(libDir, os.ModePerm)
+	}
+
+	type download struct {
+		node          *Node
+		dest          string
+		entry, locked *lock.Artifact
+	}
+	var pending []download
+
+	artifacts := map[string]*lock.Artifact{}
+	files := map[string]*lock.Artifact{}
+	for _, key := range graph.Order {
+		node := graph.Nodes[key]
+		// pom packaged artifacts (boms, aggregators) have no jar of their
+		// own and sources are built separately
+		if node.Type == "pom" || (node.Type == "" && node.Packaging == "pom") || node.Packaging == packagingSource {
+			continue
+		}
+
+		entry := &lock.Artifact{
+			GroupID:    node.GroupID,
+			ArtifactID: node.ArtifactID,
+			Version:    node.Version,
+			Classifier: node.classifier(),
+			Type:       node.Type,
+			Origin:     "maven",
+			Scope:      node.Scope,
+			File:       node.libFile(),
+		}
+		entry.Dependencies, entry.Excluded = graph.lockEdges(node)
+
+		if other, ok := files[entry.File]; ok {
+			return nil, fileCollision(other, entry)
+		}
+		files[entry.File] = entry
+
+		locked := previous.Artifacts[entry.Key()]
+		if locked != nil && locked.File != entry.File {
+			os.Remove(filepath.Join(libDir, locked.File))
+			locked = nil
+		}
+
+		// Make sure a JAR file already installed still matches what was
+		// locked. One that was never locked cannot be trusted, so it is
+		// replaced by a verified copy from the store or the repositories.
+		dest := filepath.Join(libDir, entry.File)
+		if _, err := os.Stat(dest); err == nil && locked != nil && locked.Hash != "" {
+			if err := verifyFile(dest, locked.Hash); err != nil {
+				return nil, fmt.Errorf("%s has changed since it was installed: %w", dest, err)
+			}
+			entry.Repository = locked.Repository
+			entry.URL = locked.URL
+			entry.Hash = locked.Hash
+		} else {
+			pending = append(pending, download{node, dest, entry, locked})
+		}
+		artifacts[entry.Key()] = entry
+	}
+
+	errs := parallel(len(pending), func(i int) error {
+		d := pending[i]
+		return installMavenJar(graph.Repositories, d.node, d.dest, d.entry, d.locked)
+	})
+	err := joinErrors(errs, func(i int) string { return pending[i].node.Artifact.String() })
+	if err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+func fileCollision(a, b *lock.Artifact) error {
+	return fmt.Errorf("%s and %s would both be installed as %s", a, b, a.File)
+}
+
+// checkLibFiles makes sure no two artifacts share a file in lib/.
+func checkLibFiles(artifacts map[string]*lock.Artifact) error {
+	keys := make([]string, 0, len(artifacts))
+	for key := range artifacts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	files := map[string]*lock.Artifact{}
+	for _, key := range keys {
+		a := artifacts[key]
+		if other, ok := files[a.File]; ok {
+			return fileCollision(other, a)
+		}
+		files[a.File] = a
+	}
+	return nil
+}
+
+// installMavenJar places the file of node at dest, taking it from the
+// artifact store when possible and downloading and verifying it otherwise.
+// Nothing is written to dest before it has been verified.
+func installMavenJar(repositories []config.Repository, node *Node, dest string, entry, locked *lock.Artifact) error {
+	part := partPath(dest)
+	key := mavenStoreKey(node.filePath())
+	if cacheable(node.Version) {
+		if stored, ok := linkFromStore(key, part); ok {
+			err := verifyFile(part, stored.Hash)
+			if err == nil && locked != nil && locked.Hash != "" {
+				err = verifyFile(part, locked.Hash)
+			}
+			if err == nil {
+				entry.Repository = stored.Repository
+				entry.URL = stored.URL
+				entry.Hash = stored.Hash
+				if locked != nil && locked.Hash != "" {
+					entry.Hash = locked.Hash
+				}
+				return os.Rename(part, dest)
+			}
+			downloads.Printf("Ignoring stored copy of %s: %v\n", node.Artifact, err)
+			os.Remove(part)
+		}
+	}
+
+	served, err := downloadArtifact(repositories, node.Artifact, part)
+	if err != nil {
+		return err
+	}
+	hash, err := verifyDownload(served, node.filePath(), part)
+	if err == nil && locked != nil && locked.Hash != "" {
+		if lockErr := verifyFile(part, locked.Hash); lockErr != nil {
+			err = fmt.Errorf("%s differs from the locked artifact: %w", node.Artifact, lockErr)
+		}
+	}
+	if err != nil {
+		os.Remove(part)
+		return err
+	}
+	if err := os.Rename(part, dest); err != nil {
+		return err
+	}
+
+	entry.Repository = served.ID
+	entry.URL = served.URL + "/" + node.filePath()
+	entry.Hash = hash
+	if cacheable(node.Version) {
+		addToStore(key, dest, store.Entry{Hash: entry.Hash, Repository: entry.Repository, URL: entry.URL})
+	}
+	return nil
+}
+
+// installGitHub downloads the jar of the release of user/repo that dep is
+// pinned to, or of the latest release. Offline, the release recorded in the
+// lock is installed instead.
+func installGitHub(user, repo string, dep config.Dependency, libDir string, previous *lock.Lock) (*lock.Artifact, error) {
+	scope := dep.Scope
+	if offline() {
+		locked, ok := previous.Artifacts[user+"/"+repo]
+		if !ok {
+			return nil, fmt.Errorf("%w, no release of %s/%s is locked", errOffline, user, repo)
+		}
+		if dep.Tag != "" && locked.Version != dep.Tag {
+			return nil, fmt.Errorf("%w, release %s of %s/%s is not locked", errOffline, dep.Tag, user, repo)
+		}
+		if _, err := os.Stat(libDir); os.IsNotExist(err) {
+			os.Mkdir(libDir, os.ModePerm)
+		}
+		if err := installLocked(locked, nil, libDir); err != nil {
+			return nil, err
+		}
+		entry := *locked
+		entry.Scope = config.Dependency{Scope: scope}.EffectiveScope()
+		return &entry, nil
+	}
+
+	release, err := fetchRelease(user, repo, dep.Tag)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := selectAsset(release, dep.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %w", user, repo, err)
+	}
+
+	jarDownloadURL := asset.DonwloadUrl
+	jarFileName := filepath.Base(jarDownloadURL)
+
+	if _, err := os.Stat(libDir); os.IsNotExist(err) {
+		os.Mkdir(libDir, os.ModePerm)
+	}
+	dest := filepath.Join(libDir, jarFileName)
+
+	entry := &lock.Artifact{
+		GroupID:    user,
+		ArtifactID: repo,
+		Version:    release.TagName,
+		Origin:     "github",
+		Scope:      config.Dependency{Scope: scope}.EffectiveScope(),
+		File:       jarFileName,
+		Repository: "github",
+		URL:        jarDownloadURL,
+	}
+	locked := previous.Artifacts[entry.Key()]
+	if locked != nil && locked.File != jarFileName {
+		os.Remove(filepath.Join(libDir, locked.File))
+		locked = nil
+	}
+	// Another release, or another asset of it, is new content
+	if locked != nil && locked.URL != jarDownloadURL {
+		locked = nil
+	}
+
+	key := urlStoreKey(jarDownloadURL)
+	part := partPath(dest)
+	stored, fromStore := linkFromStore(key, part)
+	if !fromStore {
+		if err := downloadFile(user+"/"+repo, jarDownloadURL, part); err != nil {
+			return nil, err
+		}
+	}
+
+	// GitHub publishes no checksums, so the first download is trusted and
+	// every later one has to match it.
+	if locked != nil && locked.Hash != "" {
+		if err := verifyFile(part, locked.Hash); err != nil {
+			os.Remove(part)
+			return nil, fmt.Errorf("%s differs from the locked artifact: %w", jarFileName, err)
+		}
+	}
+	if fromStore {
+		if err := verifyFile(part, stored.Hash); err != nil {
+			os.Remove(part)
+			return nil, fmt.Errorf("stored copy of %s is corrupt: %w", jarFileName, err)
+		}
+		entry.Hash = stored.Hash
+	} else {
+		hash, err := fileChecksum(part, "sha256")
+		if err != nil {
+			return nil, err
+		}
+		entry.Hash = "sha256:" + hash
+	}
+	if err := os.Rename(part, dest); err != nil {
+		return nil, err
+	}
+
+	if !fromStore {
+		addToStore(key, dest, store.Entry{Hash: entry.Hash, Repository: entry.Repository, URL: entry.URL})
+	}
+	return entry, nil
+}
+
+// githubTag returns the tag of the release to install for the github
+// dependency name. Without a tag in amber.toml, the locked release is kept
+// until the declaration changes, so only jpkg update moves it.
+func githubTag(name string, dep config.Dependency, previous *lock.Lock) string {
+	if dep.Tag != "" || previous.Changed(name, dep) {
+		return dep.Tag
+	}
+	if locked, ok := previous.Artifacts[name]; ok {
+		return locked.Version
+	}
+	return ""
+}
+
+// writeLock records the installed artifacts together with the current
+// dependency declarations of amber.toml.
+func writeLock(strategy string, artifacts map[string]*lock.Artifact) error {
+	if err := checkLibFiles(artifacts); err != nil {
+		return err
+	}
+	cfg, err := config.GetTomlConfig()
+	if err != nil {
+		return err
+	}
+
+	l := lock.New()
+	l.Strategy = strategy
+	l.Overrides = cfg.Overrides
+	for name, dep := range cfg.Dependencies {
+		l.Declared[name] = dep
+	}
+	l.Artifacts = artifacts
+	return l.Write()
+}
+
+// installProject resolves roots together with the dependencies of
+// everything cfg builds from source, installs the graph into libDir and
+// builds the sources. It returns the graph and the lock entries.
+func installProject(cfg *config.Config, roots []Artifact, libDir string, previous *lock.Lock) (*Graph, map[string]*lock.Artifact, error) {
+	sources, err := fetchSources(cfg, previous)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer removeSources(sources)
+
+	graph, err := resolveProject(cfg, roots, sources)
+	if err != nil {
+		return nil, nil, err
+	}
+	artifacts, err := installGraph(graph, libDir, previous)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Sources are built one at a time once everything they need is there
+	for _, source := range sources {
+		entry, err := installSource(source, graph, artifacts, libDir, previous)
+		if err != nil {
+			return nil, nil, err
+		}
+		artifacts[entry.Key()] = entry
+	}
+	return graph, artifacts, nil
+}
+
+// Install resolves every dependency declared in amber.toml, installs it
+// into libDir and rewrites the lock file.
+func Install(libDir string) error {
+	cfg, err := config.GetTomlConfig()
+	if err != nil {
+		return err
+	}
+	previous, err := lock.Read()
+	if err != nil {
+		return err
+	}
+
+	// Offline, an up to date lock file is all there is to resolve from
+	if offline() && len(previous.Artifacts) > 0 && len(previous.Drift(cfg)) == 0 {
+		return InstallFrozen(libDir)
+	}
+
+	roots, err := mavenRoots(cfg)
+	if err != nil {
+		return err
+	}
+	graph, artifacts, err := installProject(cfg, roots, libDir, previous)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, name := range cfg.DependencyNames() {
+		if dep := cfg.Dependencies[name]; dep.Origin != "github" || dep.Build {
+			continue
+		}
+		if len(strings.Split(name, "/")) != 2 {
+			return fmt.Errorf("invalid github dependency name %q", name)
+		}
+		names = append(names, name)
+	}
+
+	installed := make([]*lock.Artifact, len(names))
+	errs := parallel(len(names), func(i int) error {
+		parts := strings.Split(names[i], "/")
+		dep := cfg.Dependencies[names[i]]
+		dep.Tag = githubTag(names[i], dep, previous)
+		artifact, err := installGitHub(parts[0], parts[1], dep, libDir, previous)
+		installed[i] = artifact
+		return err
+	})
+
+	var failed []string
+	for i, name := range names {
+		if errs[i] != nil {
+			fmt.Println("Failed to install from GitHub:", errs[i])
+			failed = append(failed, name)
+			continue
+		}
+		artifacts[installed[i].Key()] = installed[i]
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to install %s", strings.Join(failed, ", "))
+	}
+
+	return writeLock(graph.Strategy, artifacts)
+}
+
+// InstallFrozen installs exactly the artifacts recorded in the lock file
+// without resolving anything. It fails when amber.toml no longer matches
+// the lock file.
+func InstallFrozen(libDir string) error {
+	cfg, err := config.GetTomlConfig()
+	if err != nil {
+		return err
+	}
+	l, err := lock.Read()
+	if err != nil {
+		return err
+	}
+	if len(l.Artifacts) == 0 && len(cfg.Dependencies) > 0 {
+		return fmt.Errorf("%s is missing or outdated, run jpkg install first", lock.FileName)
+	}
+	if drift := l.Drift(cfg); len(drift) > 0 {
+		return fmt.Errorf("amber.toml does not match %s:\n  %s", lock.FileName, strings.Join(drift, "\n  "))
+	}
+
+	repositories, err := projectRepositories(cfg)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(libDir); os.IsNotExist(err) {
+		os.Mkdir(libDir, os.ModePerm)
+	}
+	var keys, built []string
+	for _, key := range l.Keys() {
+		if l.Artifacts[key].Built {
+			built = append(built, key)
+		} else {
+			keys = append(keys, key)
+		}
+	}
+	errs := parallel(len(keys), func(i int) error {
+		return installLocked(l.Artifacts[keys[i]], repositories, libDir)
+	})
+	if err := joinErrors(errs, func(i int) string { return l.Artifacts[keys[i]].String() }); err != nil {
+		return err
+	}
+
+	// Sources are built once everything they need is installed
+	for _, key := range built {
+		a := l.Artifacts[key]
+		if a.Origin == "path" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(libDir, a.File)); err == nil {
+			continue
+		}
+		if err := rebuildLocked(l, a, libDir); err != nil {
+			return err
+		}
+	}
+	_, err = BuildPathDependencies(libDir)
+	return err
+}
+
+// installLocked makes sure the jar recorded in the lock is present in
+// libDir with exactly the locked content.
+func installLocked(a *lock.Artifact, repositories []config.Repository, libDir string) error {
+	if a.Hash == "" {
+		return fmt.Errorf("%s has no locked hash", a)
+	}
+
+	dest := filepath.Join(libDir, a.File)
+	if _, err := os.Stat(dest); err == nil {
+		if err := verifyFile(dest, a.Hash); err != nil {
+			return fmt.Errorf("%s has changed since it was installed: %w", dest, err)
+		}
+		return nil
+	}
+
+	coordinates := Artifact{GroupID: a.GroupID, ArtifactID: a.ArtifactID, Version: a.Version, Classifier: a.Classifier, Type: a.Type}
+	key := urlStoreKey(a.URL)
+	if a.Origin == "maven" {
+		key = mavenStoreKey(coordinates.filePath())
+	}
+	part := partPath(dest)
+	if _, ok := linkFromStore(key, part); ok {
+		if err := verifyFile(part, a.Hash); err == nil {
+			return os.Rename(part, dest)
+		}
+		os.Remove(part)
+	}
+
+	err := errors.New("no download url recorded")
+	if a.URL != "" {
+		err = downloadFile(a.String(), a.URL, part)
+	}
+	// The recorded url may be unreachable from here (e.g. another mirror)
+	if err != nil && a.Origin == "maven" {
+		_, err = downloadArtifact(repositories, coordinates, part)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", a, err)
+	}
+
+	if err := verifyFile(part, a.Hash); err != nil {
+		os.Remove(part)
+		return fmt.Errorf("%s differs from the locked artifact: %w", a, err)
+	}
+	if err := os.Rename(part, dest); err != nil {
+		return err
+	}
+	addToStore(key, dest, store.Entry{Hash: a.Hash, Repository: a.Repository, URL: a.URL})
+	return nil
+}
+
+// Remove deletes the dependency name from amber.toml together with every
+// installed artifact no other dependency still needs. name may also be a
+// bare artifactId when it is unambiguous.
+func Remove(name, libDir string) error {
+	cfg, err := config.GetTomlConfig()
+	if err != nil {
+		return err
+	}
+	name, err = declaredName(cfg, name)
+	if err != nil {
+		return err
+	}
+	l, err := lock.Read()
+	if err != nil {
+		return err
+	}
+
+	if err := config.RemoveDependency(name); err != nil {
+		return err
+	}
+	cfg, err = config.GetTomlConfig()
+	if err != nil {
+		return err
+	}
+
+	l.Declared = map[string]config.Dependency{}
+	for declared, dep := range cfg.Dependencies {
+		l.Declared[declared] = dep
+	}
+	for _, a := range l.Prune() {
+		if err := os.Remove(filepath.Join(libDir, a.File)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		fmt.Printf("Removed %s\n", a)
+	}
+	return l.Write()
+}
+
+// declaredName finds the amber.toml name of a dependency given either its
+// full name or its artifactId.
+func declaredName(cfg *config.Config, name string) (string, error) {
+	if _, ok := cfg.Dependencies[name]; ok {
+		return name, nil
+	}
+
+	var matches []string
+	for _, declared := range cfg.DependencyNames() {
+		if strings.HasSuffix(declared, "/"+name) {
+			matches = append(matches, declared)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%s is not a dependency of this project", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%s is ambiguous, use one of %s", name, strings.Join(matches, ", "))
+	}
+}
@@ -0,0 +1,107 @@
+package downloader
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"jpkg/pkg/config"
+	"jpkg/pkg/lock"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// publishTestJar puts a jar of a with the given content and its sha1
+// sidecar into the maven layout repository at dir.
+func publishTestJar(t *testing.T, dir string, a Artifact, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(a.filePath()))
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum([]byte(content))
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".sha1", []byte(hex.EncodeToString(sum[:])), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstallSameArtifactID(t *testing.T) {
+	useSettings(t, "")
+	repo := t.TempDir()
+	first := Artifact{GroupID: "org.one", ArtifactID: "core", Version: "1.0", Scope: config.ScopeCompile}
+	second := Artifact{GroupID: "org.two", ArtifactID: "core", Version: "1.0", Scope: config.ScopeRuntime}
+	publishTestJar(t, repo, first, "first")
+	publishTestJar(t, repo, second, "second")
+
+	graph := &Graph{
+		Nodes: map[string]*Node{
+			first.Key():  {Artifact: first},
+			second.Key(): {Artifact: second},
+		},
+		Order:        []string{first.Key(), second.Key()},
+		Repositories: []config.Repository{{ID: "test", URL: "file://" + filepath.ToSlash(repo)}},
+	}
+	libDir := filepath.Join(t.TempDir(), "lib")
+	artifacts, err := installGraph(graph, libDir, lock.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		a       Artifact
+		content string
+	}{{first, "first"}, {second, "second"}} {
+		entry := artifacts[tt.a.Key()]
+		if entry == nil {
+			t.Fatalf("%s is not installed", tt.a)
+		}
+		content, err := os.ReadFile(filepath.Join(libDir, entry.File))
+		if err != nil || string(content) != tt.content {
+			t.Errorf("%s = %q, %v, want %q", entry.File, content, err, tt.content)
+		}
+	}
+}
+
+func TestCheckLibFiles(t *testing.T) {
+	artifacts := map[string]*lock.Artifact{
+		"org.one:core": {GroupID: "org.one", ArtifactID: "core", Version: "1.0", Origin: "maven", File: "org.one.core-1.0.jar"},
+		"org.two:core": {GroupID: "org.two", ArtifactID: "core", Version: "1.0", Origin: "maven", File: "org.two.core-1.0.jar"},
+	}
+	if err := checkLibFiles(artifacts); err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts["one/core"] = &lock.Artifact{GroupID: "one", ArtifactID: "core", Version: "1.0", Origin: "github", File: "org.one.core-1.0.jar"}
+	err := checkLibFiles(artifacts)
+	if err == nil || !strings.Contains(err.Error(), "one/core:1.0 and org.one:core:1.0 would both be installed as org.one.core-1.0.jar") {
+		t.Errorf("checkLibFiles = %v", err)
+	}
+}
+
+func TestGitHubTag(t *testing.T) {
+	previous := lock.New()
+	previous.Declared["u/untagged"] = config.Dependency{Origin: "github"}
+	previous.Artifacts["u/untagged"] = &lock.Artifact{GroupID: "u", ArtifactID: "untagged", Version: "v1.2", Origin: "github"}
+	previous.Declared["u/tagged"] = config.Dependency{Origin: "github", Tag: "v1.0"}
+	previous.Artifacts["u/tagged"] = &lock.Artifact{GroupID: "u", ArtifactID: "tagged", Version: "v1.0", Origin: "github"}
+
+	tests := []struct {
+		name string
+		dep  config.Dependency
+		want string
+	}{
+		{"u/untagged", config.Dependency{Origin: "github"}, "v1.2"},
+		{"u/untagged", config.Dependency{Origin: "github", Scope: config.ScopeCompile}, "v1.2"},
+		// a changed declaration is resolved again
+		{"u/untagged", config.Dependency{Origin: "github", Asset: "*-all.jar"}, ""},
+		{"u/tagged", config.Dependency{Origin: "github", Tag: "v2.0"}, "v2.0"},
+		{"u/new", config.Dependency{Origin: "github"}, ""},
+	}
+	for _, tt := range tests {
+		if got := githubTag(tt.name, tt.dep, previous); got != tt.want {
+			t.Errorf("githubTag(%s, %+v) = %q, want %q", tt.name, tt.dep, got, tt.want)
+		}
+	}
+}
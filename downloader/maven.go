@@ -0,0 +1,129 @@
+package downloader
+
+import (
+	"fmt"
+	"jpkg/pkg/config"
+	"jpkg/pom"
+	"strings"
+)
+
+type Artifact struct {
+	GroupID    string
+	ArtifactID string
+	Version    string
+	// Classifier and Type select another file than the main jar, they
+	// share the pom of the main artifact.
+	Classifier string
+	Type       string
+	Scope      string
+	// Exclusions are the groupId:artifactId patterns left out of the
+	// dependencies of this artifact.
+	Exclusions []string
+}
+
+// Key identifies an artifact regardless of its version. Every classifier
+// of an artifact is an artifact of its own.
+func (a Artifact) Key() string {
+	if classifier := a.classifier(); classifier != "" {
+		return a.GroupID + ":" + a.ArtifactID + ":" + classifier
+	}
+	return a.GroupID + ":" + a.ArtifactID
+}
+
+func (a Artifact) String() string {
+	return a.Key() + ":" + a.Version
+}
+
+func (a Artifact) classifier() string {
+	return config.TypeClassifier(a.Type, a.Classifier)
+}
+
+func (a Artifact) fileName(ext string) string {
+	return fmt.Sprintf("%s-%s.%s", a.ArtifactID, a.Version, ext)
+}
+
+// file returns the name of the artifact's own file, which carries its
+// classifier and the extension of its type.
+func (a Artifact) file() string {
+	if classifier := a.classifier(); classifier != "" {
+		return fmt.Sprintf("%s-%s-%s.%s", a.ArtifactID, a.Version, classifier, config.TypeExtension(a.Type))
+	}
+	return a.fileName(config.TypeExtension(a.Type))
+}
+
+// libFile returns the name the artifact's file is installed under in lib/.
+// It starts with the groupId, like Maven's prependGroupId, so artifacts of
+// different groups never overwrite each other.
+func (a Artifact) libFile() string {
+	return a.GroupID + "." + a.file()
+}
+
+func (a Artifact) dir() string {
+	groupPath := strings.ReplaceAll(a.GroupID, ".", "/")
+	return fmt.Sprintf("%s/%s/%s", groupPath, a.ArtifactID, a.Version)
+}
+
+// path returns the location of the artifact's pom, or any other file with
+// the given extension, inside a Maven layout repository.
+func (a Artifact) path(ext string) string {
+	return a.dir() + "/" + a.fileName(ext)
+}
+
+// filePath returns the location of the artifact's own file inside a Maven
+// layout repository.
+func (a Artifact) filePath() string {
+	return a.dir() + "/" + a.file()
+}
+
+func exclusions(excluded []pom.Exclusion) []string {
+	var patterns []string
+	for _, exclusion := range excluded {
+		patterns = append(patterns, exclusion.GroupID+":"+exclusion.ArtifactID)
+	}
+	return patterns
+}
+
+// excludes reports whether any of the groupId:artifactId patterns matches
+// the artifact.
+func excludes(patterns []string, a Artifact) bool {
+	for _, pattern := range patterns {
+		groupID, artifactID, _ := strings.Cut(pattern, ":")
+		if (groupID == "*" || groupID == a.GroupID) && (artifactID == "*" || artifactID == a.ArtifactID) {
+			return true
+		}
+	}
+	return false
+}
+
+// runtimeDependencies returns the dependencies that must be on the
+// classpath of a consumer of the project.
+func runtimeDependencies(project *pom.Project) []Artifact {
+	var deps []Artifact
+	for _, dep := range project.Dependencies {
+		if dep.Scope != "" && dep.Scope != "compile" && dep.Scope != "runtime" {
+			continue
+		}
+		if dep.IsOptional() {
+			continue
+		}
+
+		if dep.Version == "" || strings.Contains(dep.Version, "${") {
+			fmt.Printf("Skipping %s:%s: version could not be determined from %s\n", dep.GroupID, dep.ArtifactID, project.ArtifactID)
+			continue
+		}
+		if strings.Contains(dep.Classifier, "${") {
+			fmt.Printf("Skipping %s:%s: classifier could not be determined from %s\n", dep.GroupID, dep.ArtifactID, project.ArtifactID)
+			continue
+		}
+		deps = append(deps, Artifact{
+			GroupID:    dep.GroupID,
+			ArtifactID: dep.ArtifactID,
+			Version:    dep.Version,
+			Classifier: dep.Classifier,
+			Type:       dep.Type,
+			Scope:      dep.Scope,
+			Exclusions: exclusions(dep.Exclusions),
+		})
+	}
+	return deps
+}
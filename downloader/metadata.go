@@ -0,0 +1,201 @@
+package downloader
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"jpkg/pkg/config"
+	"jpkg/pom"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type mavenMetadata struct {
+	Versions []string `xml:"versioning>versions>version"`
+}
+
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// Update describes the versions a dependency could be moved to. Empty
+// fields mean no newer version exists at that level. Err is set when the
+// versions of the dependency could not be checked.
+type Update struct {
+	Name    string
+	Origin  string
+	Current string
+	Patch   string
+	Minor   string
+	Latest  string
+	Err     error
+}
+
+// fetchMavenVersions lists the versions of groupID:artifactID published
+// in any of the repositories, oldest first.
+func fetchMavenVersions(repositories []config.Repository, groupID, artifactID string) ([]string, error) {
+	path := fmt.Sprintf("%s/%s/maven-metadata.xml", strings.ReplaceAll(groupID, ".", "/"), artifactID)
+
+	seen := map[string]bool{}
+	var versions []string
+	var failures []string
+	for _, repository := range repositories {
+		body, _, err := openURL(repository.URL + "/" + path)
+		if errors.Is(err, errNotFound) {
+			continue
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", repository.ID, err))
+			continue
+		}
+
+		var metadata mavenMetadata
+		err = xml.NewDecoder(body).Decode(&metadata)
+		body.Close()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", repository.ID, err))
+			continue
+		}
+		for _, version := range metadata.Versions {
+			version = strings.TrimSpace(version)
+			if version != "" && !seen[version] {
+				seen[version] = true
+				versions = append(versions, version)
+			}
+		}
+	}
+
+	if len(versions) == 0 {
+		if len(failures) > 0 {
+			return nil, fmt.Errorf("failed to read versions of %s:%s (%s)", groupID, artifactID, strings.Join(failures, "; "))
+		}
+		return nil, fmt.Errorf("no versions of %s:%s found in any repository", groupID, artifactID)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return pom.CompareVersions(versions[i], versions[j]) < 0
+	})
+	return versions, nil
+}
+
+// fetchGitHubTags lists the published release tags of user/repo, newest
+// first as returned by GitHub.
+func fetchGitHubTags(user, repo string) ([]string, error) {
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases", user, repo)
+	resp, err := httpGet(apiURL, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list releases of %s/%s: %s", user, repo, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var releases []githubRelease
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	for _, release := range releases {
+		if !release.Draft && !release.Prerelease {
+			tags = append(tags, release.TagName)
+		}
+	}
+	return tags, nil
+}
+
+// findUpdates picks the newest version overall, within the current major
+// version and within the current major.minor version. Pre-releases are
+// only considered when the current version is one itself.
+func findUpdates(current string, versions []string) (patch, minor, latest string) {
+	stableOnly := pom.IsStable(current)
+	major, hasMajor := pom.VersionPrefix(current, 1)
+	majorMinor, hasMinor := pom.VersionPrefix(current, 2)
+
+	for _, version := range versions {
+		if stableOnly && !pom.IsStable(version) {
+			continue
+		}
+		if pom.CompareVersions(version, current) <= 0 {
+			continue
+		}
+
+		if latest == "" || pom.CompareVersions(version, latest) > 0 {
+			latest = version
+		}
+		if prefix, ok := pom.VersionPrefix(version, 1); ok && hasMajor && prefix == major {
+			if minor == "" || pom.CompareVersions(version, minor) > 0 {
+				minor = version
+			}
+		}
+		if prefix, ok := pom.VersionPrefix(version, 2); ok && hasMinor && prefix == majorMinor {
+			if patch == "" || pom.CompareVersions(version, patch) > 0 {
+				patch = version
+			}
+		}
+	}
+	return patch, minor, latest
+}
+
+// Outdated checks every dependency declared in amber.toml for newer
+// versions. A dependency that cannot be checked is reported with its error
+// and does not stop the others from being checked.
+func Outdated() ([]Update, error) {
+	cfg, err := config.GetTomlConfig()
+	if err != nil {
+		return nil, err
+	}
+	repositories, err := projectRepositories(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []Update
+	for _, name := range cfg.DependencyNames() {
+		dep := cfg.Dependencies[name]
+		if dep.Origin != "maven" && dep.Origin != "github" {
+			continue
+		}
+		update := Update{Name: name, Origin: dep.Origin, Current: dep.Version}
+		if dep.Origin == "github" {
+			update.Current = dep.Tag
+		}
+		parts := strings.Split(strings.SplitN(name, ":", 2)[0], "/")
+		if len(parts) != 2 {
+			update.Err = fmt.Errorf("invalid dependency name %q", name)
+			updates = append(updates, update)
+			continue
+		}
+
+		switch dep.Origin {
+		case "maven":
+			versions, err := fetchMavenVersions(repositories, parts[0], parts[1])
+			if err != nil {
+				update.Err = err
+				break
+			}
+			update.Patch, update.Minor, update.Latest = findUpdates(dep.Version, versions)
+		case "github":
+			tags, err := fetchGitHubTags(parts[0], parts[1])
+			if err != nil {
+				update.Err = err
+				break
+			}
+			if len(tags) > 0 && tags[0] != dep.Tag {
+				update.Latest = tags[0]
+			}
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
@@ -0,0 +1,72 @@
+package downloader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindUpdates(t *testing.T) {
+	tests := []struct {
+		name                 string
+		current              string
+		versions             []string
+		patch, minor, latest string
+	}{
+		{
+			name:     "stable versions only",
+			current:  "1.2.3",
+			versions: []string{"1.2.3", "1.2.4", "1.2.5", "1.3.0", "1.4.0-rc1", "2.0.0", "2.1.0-beta"},
+			patch:    "1.2.5", minor: "1.3.0", latest: "2.0.0",
+		},
+		{
+			name:     "pre-releases of a pre-release",
+			current:  "2.0.0-rc1",
+			versions: []string{"1.9", "2.0.0-rc1", "2.0.0-rc2", "2.0.0", "2.1.0-alpha1"},
+			patch:    "2.0.0", minor: "2.1.0-alpha1", latest: "2.1.0-alpha1",
+		},
+		{
+			name:     "current without a numeric prefix",
+			current:  "r09",
+			versions: []string{"r08", "r09", "r10", "11.0"},
+			latest:   "11.0",
+		},
+		{
+			name:     "candidates without a numeric prefix",
+			current:  "1.0",
+			versions: []string{"nightly", "1.1", "v2"},
+			minor:    "1.1", latest: "1.1",
+		},
+		{
+			name:     "service pack of a release",
+			current:  "1.0",
+			versions: []string{"1.0-sp1"},
+			patch:    "1.0-sp1", minor: "1.0-sp1", latest: "1.0-sp1",
+		},
+		{
+			name:     "release of a release candidate",
+			current:  "1.0-rc1",
+			versions: []string{"1.0-rc2", "1.0"},
+			patch:    "1.0", minor: "1.0", latest: "1.0",
+		},
+		{
+			name:     "qualified versions",
+			current:  "32.1.2-jre",
+			versions: []string{"32.1.2-android", "32.1.3-android", "32.1.3-jre", "33.0.0-jre"},
+			patch:    "32.1.3-jre", minor: "32.1.3-jre", latest: "33.0.0-jre",
+		},
+		{
+			name:     "up to date",
+			current:  "2.0",
+			versions: []string{"1.0", "2.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, minor, latest := findUpdates(tt.current, tt.versions)
+			if patch != tt.patch || minor != tt.minor || latest != tt.latest {
+				t.Errorf("findUpdates(%s, %s) = %q, %q, %q, want %q, %q, %q",
+					tt.current, strings.Join(tt.versions, " "), patch, minor, latest, tt.patch, tt.minor, tt.latest)
+			}
+		})
+	}
+}
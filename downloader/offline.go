@@ -0,0 +1,55 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// errOffline is returned for anything that would need the network while
+// jpkg works offline.
+var errOffline = errors.New("working offline")
+
+var forceOffline bool
+
+// SetOffline keeps jpkg off the network for the rest of the run, as the
+// --offline flag does.
+func SetOffline() {
+	forceOffline = true
+}
+
+// offline reports whether the network must not be used.
+func offline() bool {
+	if forceOffline {
+		return true
+	}
+	settings, err := userSettings()
+	return err == nil && settings.IsOffline()
+}
+
+// offlineError lists everything that is not available locally.
+func offlineError(missing []string) error {
+	sort.Strings(missing)
+	return fmt.Errorf("%w, not available locally:\n  %s", errOffline, strings.Join(missing, "\n  "))
+}
+
+// joinErrors combines the errors returned by parallel. Everything that
+// only failed because jpkg is offline is listed in a single error.
+func joinErrors(errs []error, name func(i int) string) error {
+	var missing []string
+	var failures []error
+	for i, err := range errs {
+		switch {
+		case err == nil:
+		case errors.Is(err, errOffline):
+			missing = append(missing, name(i))
+		default:
+			failures = append(failures, err)
+		}
+	}
+	if len(missing) > 0 {
+		failures = append(failures, offlineError(missing))
+	}
+	return errors.Join(failures...)
+}
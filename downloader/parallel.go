@@ -0,0 +1,39 @@
+package downloader
+
+import (
+	"jpkg/pkg/config"
+	"sync"
+)
+
+// downloadConcurrency returns the number of downloads to run at once.
+func downloadConcurrency() int {
+	settings, err := userSettings()
+	if err != nil {
+		return config.DefaultConcurrency
+	}
+	return settings.DownloadConcurrency()
+}
+
+// parallel calls work for every index below n on a bounded pool of
+// goroutines and returns the errors by index.
+func parallel(n int, work func(i int) error) []error {
+	errs := make([]error, n)
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(downloadConcurrency(), n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = work(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,212 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"jpkg/pkg/config"
+	"jpkg/pkg/lock"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// localVersion is the version path dependencies have in the graph.
+const localVersion = "local"
+
+// pathSources finds the project of a path dependency declared by the
+// project in baseDir, preceded by the projects of its own path
+// dependencies. Projects in seen are not returned again.
+func pathSources(name string, dep config.Dependency, baseDir string, seen map[string]*sourceBuild) ([]*sourceBuild, error) {
+	if dep.Path == "" {
+		return nil, fmt.Errorf("path dependency %s has no path", name)
+	}
+	root := filepath.FromSlash(dep.Path)
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(baseDir, root)
+	}
+
+	nested := baseDir != "."
+	if existing, ok := seen[name]; ok {
+		if !sameDir(existing.Root, root) {
+			return nil, fmt.Errorf("two path dependencies are named %s (%s and %s)", name, existing.Root, root)
+		}
+		existing.Nested = existing.Nested && nested
+		return nil, nil
+	}
+	if _, err := os.Stat(filepath.Join(root, "amber.toml")); err != nil {
+		return nil, fmt.Errorf("path dependency %s: %s is not a jpkg project", name, root)
+	}
+
+	dir, err := os.MkdirTemp("", "jpkg-build-*")
+	if err != nil {
+		return nil, err
+	}
+	source := &sourceBuild{
+		Artifact: Artifact{GroupID: "path", ArtifactID: name, Version: localVersion, Scope: dep.EffectiveScope()},
+		Name:     name,
+		Origin:   "path",
+		Root:     root,
+		Dir:      dir,
+		URL:      filepath.ToSlash(root),
+		Nested:   nested,
+	}
+	seen[name] = source
+	if err := source.readConfig(); err != nil {
+		return nil, err
+	}
+	if source.Hash, err = fingerprint(root); err != nil {
+		return nil, err
+	}
+
+	var sources []*sourceBuild
+	for _, nested := range source.Paths {
+		found, err := pathSources(nested, source.Config.Dependencies[nested], root, seen)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, found...)
+	}
+	return append(sources, source), nil
+}
+
+func sameDir(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// projectSourceDir returns the sources directory of the project at root.
+func projectSourceDir(root string) string {
+	var cfg config.Config
+	if read, err := config.ReadTomlConfig(filepath.Join(root, "amber.toml")); err == nil {
+		cfg = *read
+	}
+	return cfg.SourceDir()
+}
+
+// fingerprint hashes the sources, resources and amber.toml of a project
+// so that changes to any of them can be detected.
+func fingerprint(root string) (string, error) {
+	var files []string
+	srcDir := projectSourceDir(root)
+	for _, dir := range []string{srcDir, filepath.Join(filepath.Dir(srcDir), "resources")} {
+		err := filepath.Walk(filepath.Join(root, dir), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	files = append(files, filepath.Join(root, "amber.toml"))
+	sort.Strings(files)
+
+	hash := sha256.New()
+	for _, path := range files {
+		rel, _ := filepath.Rel(root, path)
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\x00", filepath.ToSlash(rel))
+		_, err = io.Copy(hash, file)
+		file.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// The fingerprint of the sources a path dependency was last built from is
+// kept next to the project's build output.
+func stampPath(jarFileName string) string {
+	return filepath.Join(".jpkg", "path", jarFileName+".sha256")
+}
+
+func readStamp(jarFileName string) string {
+	content, err := os.ReadFile(stampPath(jarFileName))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+func writeStamp(jarFileName, hash string) error {
+	path := stampPath(jarFileName)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(hash+"\n"), 0644)
+}
+
+// BuildPathDependencies rebuilds the locked path dependencies whose
+// sources changed since they were last built, along with every path
+// dependency that needs them. It reports whether anything was rebuilt.
+func BuildPathDependencies(libDir string) (bool, error) {
+	l, err := lock.Read()
+	if err != nil {
+		return false, err
+	}
+
+	rebuilt := map[string]bool{}
+	for _, a := range pathOrder(l) {
+		stale := false
+		for _, dep := range l.Transitive(a.Key()) {
+			stale = stale || rebuilt[dep.Key()]
+		}
+		if _, err := os.Stat(filepath.Join(libDir, a.File)); err != nil {
+			stale = true
+		}
+		if !stale {
+			hash, err := fingerprint(filepath.FromSlash(a.URL))
+			if err != nil {
+				return len(rebuilt) > 0, fmt.Errorf("path dependency %s: %w", a.ArtifactID, err)
+			}
+			stale = readStamp(a.File) != hash
+		}
+		if !stale {
+			continue
+		}
+
+		if err := rebuildLocked(l, a, libDir); err != nil {
+			return len(rebuilt) > 0, err
+		}
+		rebuilt[a.Key()] = true
+	}
+	return len(rebuilt) > 0, nil
+}
+
+// pathOrder returns the locked path dependencies, each after the path
+// dependencies it needs.
+func pathOrder(l *lock.Lock) []*lock.Artifact {
+	var order []*lock.Artifact
+	visited := map[string]bool{}
+	var visit func(a *lock.Artifact)
+	visit = func(a *lock.Artifact) {
+		if visited[a.Key()] {
+			return
+		}
+		visited[a.Key()] = true
+		for _, dep := range l.Transitive(a.Key()) {
+			if dep.Origin == "path" {
+				visit(dep)
+			}
+		}
+		order = append(order, a)
+	}
+	for _, key := range l.Keys() {
+		if a := l.Artifacts[key]; a.Origin == "path" {
+			visit(a)
+		}
+	}
+	return order
+}
@@ -0,0 +1,152 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// progress shows every running download as one block of lines that is
+// redrawn in place. When stdout is not a terminal it prints a plain line
+// per finished download instead.
+type progress struct {
+	mu      sync.Mutex
+	out     io.Writer
+	tty     bool
+	active  []*transfer
+	drawn   int
+	drawnAt time.Time
+}
+
+// transfer is a single download shown by progress.
+type transfer struct {
+	progress *progress
+	name     string
+	size     int64
+	done     int64
+}
+
+var downloads = newProgress(os.Stdout)
+
+func newProgress(out *os.File) *progress {
+	return &progress{out: out, tty: term.IsTerminal(int(out.Fd()))}
+}
+
+// start adds a download of size bytes, or of unknown size when size is not
+// positive, of which done bytes are already there.
+func (p *progress) start(name string, done, size int64) *transfer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := &transfer{progress: p, name: name, size: size, done: done}
+	p.active = append(p.active, t)
+	p.redraw()
+	return t
+}
+
+func (t *transfer) Write(b []byte) (int, error) {
+	p := t.progress
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t.done += int64(len(b))
+	if time.Since(p.drawnAt) > 100*time.Millisecond {
+		p.redraw()
+	}
+	return len(b), nil
+}
+
+// finish removes the download from the view and logs its outcome.
+func (p *progress) finish(t *transfer, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, active := range p.active {
+		if active == t {
+			p.active = append(p.active[:i], p.active[i+1:]...)
+			break
+		}
+	}
+	p.clear()
+	if err != nil {
+		fmt.Fprintf(p.out, "Failed to download %s: %v\n", t.name, err)
+	} else {
+		fmt.Fprintf(p.out, "Downloaded %s (%s)\n", t.name, formatBytes(t.done))
+	}
+	p.redraw()
+}
+
+// Printf prints a message above the running downloads.
+func (p *progress) Printf(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	fmt.Fprintf(p.out, format, args...)
+	p.redraw()
+}
+
+// clear erases the lines drawn by the last redraw.
+func (p *progress) clear() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.out, "\033[%dA\033[J", p.drawn)
+		p.drawn = 0
+	}
+}
+
+func (p *progress) redraw() {
+	if !p.tty {
+		return
+	}
+	p.clear()
+
+	width := 80
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		width = w
+	}
+	for _, t := range p.active {
+		fmt.Fprintln(p.out, truncate(t.line(), width-1))
+	}
+	p.drawn = len(p.active)
+	p.drawnAt = time.Now()
+}
+
+func (t *transfer) line() string {
+	name := truncate(t.name, 28)
+	if t.size <= 0 {
+		return fmt.Sprintf("%-28s %s", name, formatBytes(t.done))
+	}
+
+	const barWidth = 20
+	filled := int(t.done * barWidth / t.size)
+	if filled > barWidth {
+		filled = barWidth
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat(" ", barWidth-filled)
+	return fmt.Sprintf("%-28s %3d%% |%s| %s / %s", name, t.done*100/t.size, bar, formatBytes(t.done), formatBytes(t.size))
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f kB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
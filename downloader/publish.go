@@ -0,0 +1,380 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"jpkg/pkg/config"
+	"jpkg/pom"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// publishedChecksums are uploaded next to every published file.
+var publishedChecksums = []string{"md5", "sha1", "sha256", "sha512"}
+
+// Publication is the built project handed to Publish: its jar and the
+// sources and javadoc jars that go with it.
+type Publication struct {
+	Jar     string
+	Sources string
+	Javadoc string
+}
+
+type pomLicense struct {
+	Name string `xml:"name"`
+}
+
+type pomExclusion struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+}
+
+type pomExclusions struct {
+	Exclusions []pomExclusion `xml:"exclusion"`
+}
+
+type pomDependency struct {
+	GroupID    string         `xml:"groupId"`
+	ArtifactID string         `xml:"artifactId"`
+	Version    string         `xml:"version"`
+	Classifier string         `xml:"classifier,omitempty"`
+	Type       string         `xml:"type,omitempty"`
+	Scope      string         `xml:"scope,omitempty"`
+	Exclusions *pomExclusions `xml:"exclusions,omitempty"`
+}
+
+type pomLicenses struct {
+	Licenses []pomLicense `xml:"license"`
+}
+
+type pomDependencies struct {
+	Dependencies []pomDependency `xml:"dependency"`
+}
+
+type pomDependencyManagement struct {
+	Dependencies pomDependencies `xml:"dependencies"`
+}
+
+// pomXML is the POM written for a published or exported project. The
+// properties, repositories and build only matter to exported ones.
+type pomXML struct {
+	XMLName              xml.Name                 `xml:"project"`
+	Xmlns                string                   `xml:"xmlns,attr"`
+	ModelVersion         string                   `xml:"modelVersion"`
+	GroupID              string                   `xml:"groupId"`
+	ArtifactID           string                   `xml:"artifactId"`
+	Version              string                   `xml:"version"`
+	Packaging            string                   `xml:"packaging"`
+	Name                 string                   `xml:"name"`
+	Description          string                   `xml:"description,omitempty"`
+	URL                  string                   `xml:"url,omitempty"`
+	Licenses             *pomLicenses             `xml:"licenses,omitempty"`
+	Properties           *pomProperties           `xml:"properties,omitempty"`
+	DependencyManagement *pomDependencyManagement `xml:"dependencyManagement,omitempty"`
+	Dependencies         *pomDependencies         `xml:"dependencies,omitempty"`
+	Repositories         *pomRepositories         `xml:"repositories,omitempty"`
+	Build                *pomBuild                `xml:"build,omitempty"`
+}
+
+// publishedMetadata is the maven-metadata.xml listing the versions of a
+// published artifact.
+type publishedMetadata struct {
+	XMLName     xml.Name `xml:"metadata"`
+	GroupID     string   `xml:"groupId"`
+	ArtifactID  string   `xml:"artifactId"`
+	Latest      string   `xml:"versioning>latest,omitempty"`
+	Release     string   `xml:"versioning>release,omitempty"`
+	Versions    []string `xml:"versioning>versions>version"`
+	LastUpdated string   `xml:"versioning>lastUpdated"`
+}
+
+// publishRepository returns the repository named by target, or by the
+// [publish] section when target is empty. Either is the id of a
+// [repositories] entry or a url.
+func publishRepository(cfg *config.Config, target string) (config.Repository, error) {
+	if target == "" {
+		target = cfg.Publish.Repository
+	}
+	if target == "" {
+		return config.Repository{}, errors.New("no repository to publish to, set repository in the [publish] section of amber.toml or pass --repository")
+	}
+
+	var repository config.Repository
+	if strings.Contains(target, "://") {
+		repository.URL = strings.TrimSuffix(target, "/")
+		for _, declared := range cfg.MavenRepositories() {
+			if declared.URL == repository.URL {
+				repository.ID = declared.ID
+			}
+		}
+	} else {
+		url, ok := cfg.Repositories[target]
+		if !ok {
+			return config.Repository{}, fmt.Errorf("unknown repository %q, declare it in [repositories]", target)
+		}
+		repository = config.Repository{ID: target, URL: strings.TrimSuffix(url, "/")}
+	}
+	if repository.ID != "" {
+		registerRepositories([]config.Repository{repository})
+	}
+	return repository, nil
+}
+
+// projectPOM describes the project from the metadata and maven
+// dependencies in cfg. It also returns the dependencies a POM cannot
+// express, which consumers of the POM will not get.
+func projectPOM(cfg *config.Config, a Artifact) (*pomXML, []string, error) {
+	project := &pomXML{
+		Xmlns:        "http://maven.apache.org/POM/4.0.0",
+		ModelVersion: "4.0.0",
+		GroupID:      a.GroupID,
+		ArtifactID:   a.ArtifactID,
+		Version:      a.Version,
+		Packaging:    "jar",
+		Name:         a.ArtifactID,
+		Description:  cfg.Description,
+		URL:          cfg.URL,
+	}
+	if cfg.License != "" {
+		project.Licenses = &pomLicenses{[]pomLicense{{Name: cfg.License}}}
+	}
+
+	var skipped []string
+	for _, name := range cfg.DependencyNames() {
+		dep := cfg.Dependencies[name]
+		if dep.Origin != "maven" {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", name, dep.Origin))
+			continue
+		}
+		groupID, artifactID, err := config.SplitMavenName(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		dependency := pomDependency{
+			GroupID:    groupID,
+			ArtifactID: artifactID,
+			Version:    dep.Version,
+			Classifier: dep.Classifier,
+		}
+		if dep.Type != "jar" {
+			dependency.Type = dep.Type
+		}
+		if dep.Scope != config.ScopeCompile {
+			dependency.Scope = dep.Scope
+		}
+		for _, pattern := range dep.Exclude {
+			if err := config.ValidateCoordinate(pattern, true); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if dependency.Exclusions == nil {
+				dependency.Exclusions = &pomExclusions{}
+			}
+			groupID, artifactID, _ := strings.Cut(pattern, ":")
+			dependency.Exclusions.Exclusions = append(dependency.Exclusions.Exclusions, pomExclusion{GroupID: groupID, ArtifactID: artifactID})
+		}
+		if project.Dependencies == nil {
+			project.Dependencies = &pomDependencies{}
+		}
+		project.Dependencies.Dependencies = append(project.Dependencies.Dependencies, dependency)
+	}
+
+	// Overrides become managed versions, which Maven applies to the
+	// transitive dependencies of the published artifact as well
+	overrides, err := cfg.VersionOverrides()
+	if err != nil {
+		return nil, nil, err
+	}
+	coordinates := make([]string, 0, len(overrides))
+	for coordinate := range overrides {
+		coordinates = append(coordinates, coordinate)
+	}
+	sort.Strings(coordinates)
+	if len(coordinates) > 0 {
+		project.DependencyManagement = &pomDependencyManagement{}
+	}
+	for _, coordinate := range coordinates {
+		groupID, artifactID, _ := strings.Cut(coordinate, ":")
+		managed := &project.DependencyManagement.Dependencies
+		managed.Dependencies = append(managed.Dependencies, pomDependency{GroupID: groupID, ArtifactID: artifactID, Version: overrides[coordinate]})
+	}
+
+	return project, skipped, nil
+}
+
+// marshalXML renders v as an XML document.
+func marshalXML(v any) ([]byte, error) {
+	content, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
+
+// putFile stores content at an http(s) url with a PUT request, or at the
+// path of a file:// url.
+func putFile(url string, content []byte) error {
+	if strings.HasPrefix(url, "file://") {
+		path := strings.TrimPrefix(url, "file://")
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+		part := path + ".part"
+		if err := os.WriteFile(part, content, 0644); err != nil {
+			return err
+		}
+		return os.Rename(part, path)
+	}
+
+	if offline() {
+		return fmt.Errorf("%w, cannot upload %s", errOffline, url)
+	}
+	if err := setupClient(); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	if err := authorize(req); err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &httpError{URL: url, Status: resp.Status, Code: resp.StatusCode, Upload: true}
+	}
+	return nil
+}
+
+// uploadFile stores content at url together with its checksum files.
+func uploadFile(url string, content []byte) error {
+	if err := putFile(url, content); err != nil {
+		return err
+	}
+	for _, algorithm := range publishedChecksums {
+		h, err := newHash(algorithm)
+		if err != nil {
+			return err
+		}
+		h.Write(content)
+		if err := putFile(url+"."+algorithm, []byte(hex.EncodeToString(h.Sum(nil)))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateMetadata adds the published version to the maven-metadata.xml of
+// its artifact in the repository.
+func updateMetadata(repository config.Repository, a Artifact) error {
+	url := fmt.Sprintf("%s/%s/%s/maven-metadata.xml", repository.URL, strings.ReplaceAll(a.GroupID, ".", "/"), a.ArtifactID)
+
+	metadata := publishedMetadata{GroupID: a.GroupID, ArtifactID: a.ArtifactID}
+	body, _, err := openURL(url)
+	switch {
+	case err == nil:
+		err = xml.NewDecoder(body).Decode(&metadata)
+		body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", url, err)
+		}
+	case !errors.Is(err, errNotFound):
+		return err
+	}
+
+	if !slices.Contains(metadata.Versions, a.Version) {
+		metadata.Versions = append(metadata.Versions, a.Version)
+	}
+	// Publishing a fix for an older line does not make it the latest
+	if metadata.Latest == "" || pom.CompareVersions(a.Version, metadata.Latest) > 0 {
+		metadata.Latest = a.Version
+	}
+	if !strings.HasSuffix(a.Version, "-SNAPSHOT") && (metadata.Release == "" || pom.CompareVersions(a.Version, metadata.Release) > 0) {
+		metadata.Release = a.Version
+	}
+	metadata.LastUpdated = time.Now().UTC().Format("20060102150405")
+
+	content, err := marshalXML(metadata)
+	if err != nil {
+		return err
+	}
+	return uploadFile(url, content)
+}
+
+// Publish uploads the publication and a POM generated from cfg to target,
+// a repository id or url. Released versions are never overwritten, while
+// -SNAPSHOT versions are replaced by every publish.
+func Publish(cfg *config.Config, target string, publication Publication) error {
+	groupID, artifactID, version, err := cfg.Coordinates()
+	if err != nil {
+		return err
+	}
+	repository, err := publishRepository(cfg, target)
+	if err != nil {
+		return err
+	}
+	a := Artifact{GroupID: groupID, ArtifactID: artifactID, Version: version}
+	project, skipped, err := projectPOM(cfg, a)
+	if err != nil {
+		return fmt.Errorf("failed to generate POM: %w", err)
+	}
+	pomContent, err := marshalXML(project)
+	if err != nil {
+		return err
+	}
+	for _, name := range skipped {
+		fmt.Printf("Warning: %s is not a maven dependency and is left out of the POM\n", name)
+	}
+
+	base := repository.URL + "/" + a.dir() + "/"
+	if !strings.HasSuffix(version, "-SNAPSHOT") {
+		body, _, err := openURL(base + a.fileName("pom"))
+		if err == nil {
+			body.Close()
+			return fmt.Errorf("%s is already published to %s", a, repository.URL)
+		}
+		if !errors.Is(err, errNotFound) {
+			return err
+		}
+	}
+
+	// The POM goes last, so that a failed publish of a release can be
+	// retried
+	files := []struct {
+		path       string
+		classifier string
+	}{{publication.Jar, ""}, {publication.Sources, "sources"}, {publication.Javadoc, "javadoc"}}
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		content, err := os.ReadFile(file.path)
+		if err != nil {
+			return err
+		}
+		name := Artifact{GroupID: groupID, ArtifactID: artifactID, Version: version, Classifier: file.classifier}.file()
+		if err := uploadFile(base+name, content); err != nil {
+			return err
+		}
+		fmt.Printf("Uploaded %s\n", name)
+	}
+	if err := uploadFile(base+a.fileName("pom"), pomContent); err != nil {
+		return err
+	}
+	fmt.Printf("Uploaded %s\n", a.fileName("pom"))
+
+	return updateMetadata(repository, a)
+}
@@ -0,0 +1,148 @@
+package downloader
+
+import (
+	"encoding/xml"
+	"jpkg/pkg/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// checkGolden compares got with the file of the given name in testdata.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	want, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s differs, got\n%s\nwant\n%s", name, got, want)
+	}
+}
+
+// testPublication builds a jar and sources jar of the given content.
+func testPublication(t *testing.T, content string) Publication {
+	t.Helper()
+	dir := t.TempDir()
+	publication := Publication{Jar: filepath.Join(dir, "app.jar"), Sources: filepath.Join(dir, "app-sources.jar")}
+	for _, path := range []string{publication.Jar, publication.Sources} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return publication
+}
+
+func publishVersion(t *testing.T, repo, version, content string) error {
+	t.Helper()
+	cfg := &config.Config{Group: "org.demo", Name: "app", Version: version}
+	return Publish(cfg, "file://"+filepath.ToSlash(repo), testPublication(t, content))
+}
+
+func readMetadata(t *testing.T, repo string) publishedMetadata {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(repo, "org", "demo", "app", "maven-metadata.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var metadata publishedMetadata
+	if err := xml.Unmarshal(content, &metadata); err != nil {
+		t.Fatal(err)
+	}
+	return metadata
+}
+
+func checkMetadata(t *testing.T, repo, latest, release string, versions ...string) {
+	t.Helper()
+	metadata := readMetadata(t, repo)
+	if metadata.Latest != latest || metadata.Release != release || strings.Join(metadata.Versions, ",") != strings.Join(versions, ",") {
+		t.Errorf("latest %s, release %s, versions %v, want latest %s, release %s, versions %v",
+			metadata.Latest, metadata.Release, metadata.Versions, latest, release, versions)
+	}
+}
+
+func readPublished(t *testing.T, repo, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(repo, "org", "demo", "app", filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestPublish(t *testing.T) {
+	useSettings(t, "")
+	repo := t.TempDir()
+	cfg := &config.Config{Group: "org.demo", Name: "app", Version: "1.0", Description: "Demo app", License: "MIT"}
+	cfg.AddDependency("com.google.guava/guava", config.Dependency{Origin: "maven", Version: "33.0.0-jre", Exclude: []string{"com.google.code.findbugs:jsr305", "*:checker-qual"}})
+	cfg.AddDependency("org.demo/natives:natives-linux", config.Dependency{Origin: "maven", Version: "1.0", Classifier: "natives-linux", Scope: config.ScopeRuntime})
+	cfg.AddDependency("org.demo/bom", config.Dependency{Origin: "maven", Version: "1.0", Type: "pom", Scope: config.ScopeProvided})
+	cfg.AddDependency("junit/junit", config.Dependency{Origin: "maven", Version: "4.13.2", Scope: config.ScopeTest})
+	cfg.AddDependency("user/tool", config.Dependency{Origin: "github", Tag: "v1.0"})
+	cfg.Overrides = map[string]string{"org.slf4j:slf4j-api": "2.0.9", "com.ex:b": "2.0"}
+
+	if err := Publish(cfg, "file://"+filepath.ToSlash(repo), testPublication(t, "jar")); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "publish/app-1.0.pom", []byte(readPublished(t, repo, "1.0/app-1.0.pom")))
+	for _, name := range []string{"1.0/app-1.0.jar", "1.0/app-1.0-sources.jar"} {
+		if content := readPublished(t, repo, name); content != "jar" {
+			t.Errorf("%s = %q", name, content)
+		}
+		// every file comes with its checksums
+		if sha1 := readPublished(t, repo, name+".sha1"); sha1 != "f92e777f4341930bad9b2422283c4680d00dbc06" {
+			t.Errorf("%s.sha1 = %s", name, sha1)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(repo, "org", "demo", "app", "1.0", "app-1.0-javadoc.jar")); err == nil {
+		t.Error("a javadoc jar was published without being built")
+	}
+	checkMetadata(t, repo, "1.0", "1.0", "1.0")
+}
+
+func TestPublishOlderVersion(t *testing.T) {
+	useSettings(t, "")
+	repo := t.TempDir()
+	for _, version := range []string{"1.1", "1.0.1"} {
+		if err := publishVersion(t, repo, version, version); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a fix of an older line is listed without becoming the latest
+	checkMetadata(t, repo, "1.1", "1.1", "1.1", "1.0.1")
+}
+
+func TestPublishSnapshot(t *testing.T) {
+	useSettings(t, "")
+	repo := t.TempDir()
+	if err := publishVersion(t, repo, "1.0", "release"); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"first", "second"} {
+		if err := publishVersion(t, repo, "1.1-SNAPSHOT", content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if content := readPublished(t, repo, "1.1-SNAPSHOT/app-1.1-SNAPSHOT.jar"); content != "second" {
+		t.Errorf("snapshot jar = %q, want the last one published", content)
+	}
+	// snapshots are the latest version but never a release
+	checkMetadata(t, repo, "1.1-SNAPSHOT", "1.0", "1.0", "1.1-SNAPSHOT")
+}
+
+func TestPublishReleaseOnce(t *testing.T) {
+	useSettings(t, "")
+	repo := t.TempDir()
+	if err := publishVersion(t, repo, "1.0", "first"); err != nil {
+		t.Fatal(err)
+	}
+	err := publishVersion(t, repo, "1.0", "second")
+	if err == nil || !strings.Contains(err.Error(), "is already published") {
+		t.Fatalf("republishing 1.0: %v", err)
+	}
+	if content := readPublished(t, repo, "1.0/app-1.0.jar"); content != "first" {
+		t.Errorf("released jar = %q, was overwritten", content)
+	}
+	checkMetadata(t, repo, "1.0", "1.0", "1.0")
+}
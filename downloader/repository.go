@@ -0,0 +1,146 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"jpkg/pkg/config"
+	"jpkg/pom"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var errNotFound = errors.New("not found")
+
+// openURL opens an http(s) or file:// url for reading. The returned size is
+// -1 when the length of the content is unknown.
+func openURL(url string) (io.ReadCloser, int64, error) {
+	body, size, _, err := openRange(url, 0)
+	return body, size, err
+}
+
+// openRange opens url for reading from offset on. It reports whether the
+// content really starts at offset, as servers may ignore the range and
+// send everything. The size is the length of the whole content.
+func openRange(url string, offset int64) (io.ReadCloser, int64, bool, error) {
+	if strings.HasPrefix(url, "file://") {
+		path := strings.TrimPrefix(url, "file://")
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			return nil, 0, false, fmt.Errorf("%s: %w", url, errNotFound)
+		}
+		if err != nil {
+			return nil, 0, false, err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, 0, false, err
+		}
+		if offset <= 0 || offset > info.Size() {
+			return file, info.Size(), false, nil
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return nil, 0, false, err
+		}
+		return file, info.Size(), true, nil
+	}
+
+	resp, err := httpGet(url, offset)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return resp.Body, resp.ContentLength, false, nil
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		size := int64(-1)
+		if resp.ContentLength >= 0 {
+			size = offset + resp.ContentLength
+		}
+		return resp.Body, size, true, nil
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file does not fit the content, start over
+		resp.Body.Close()
+		return openRange(url, 0)
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, 0, false, fmt.Errorf("%s: %w", url, errNotFound)
+	default:
+		resp.Body.Close()
+		return nil, 0, false, &httpError{URL: url, Status: resp.Status, Code: resp.StatusCode}
+	}
+}
+
+// projectRepositories returns the repositories declared by the project with
+// the user's mirrors applied.
+func projectRepositories(cfg *config.Config) ([]config.Repository, error) {
+	settings, err := config.GetSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	repositories := cfg.MavenRepositories()
+	for i, repository := range repositories {
+		if url, key, ok := settings.Mirror(repository.ID); ok {
+			repositories[i].URL = strings.TrimSuffix(url, "/")
+			repositories[i].Mirror = key
+		}
+	}
+	registerRepositories(repositories)
+	return repositories, nil
+}
+
+// fetchArtifact opens the given repository path from the first repository
+// that has it.
+func fetchArtifact(repositories []config.Repository, path string) (io.ReadCloser, int64, config.Repository, error) {
+	var failures []string
+	unreachable := false
+	for _, repository := range repositories {
+		body, size, err := openURL(repository.URL + "/" + path)
+		if err == nil {
+			return body, size, repository, nil
+		}
+		if errors.Is(err, errOffline) {
+			unreachable = true
+		} else if !errors.Is(err, errNotFound) {
+			failures = append(failures, fmt.Sprintf("%s: %v", repository.ID, err))
+		}
+	}
+	return nil, 0, config.Repository{}, fetchError(path, failures, unreachable)
+}
+
+// fetchError explains why path was found in none of the repositories.
+func fetchError(path string, failures []string, unreachable bool) error {
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to fetch %s (%s)", path, strings.Join(failures, "; "))
+	}
+	if unreachable {
+		return fmt.Errorf("%s: %w", path, errOffline)
+	}
+	return fmt.Errorf("%s %w in any repository", path, errNotFound)
+}
+
+func pomFetcher(repositories []config.Repository) pom.Fetcher {
+	return func(groupID, artifactID, version string) (io.ReadCloser, error) {
+		return storedPOM(repositories, Artifact{GroupID: groupID, ArtifactID: artifactID, Version: version})
+	}
+}
+
+// mergeRepositories appends the repositories of extra that repositories
+// does not have an id for.
+func mergeRepositories(repositories, extra []config.Repository) []config.Repository {
+	for _, repository := range extra {
+		known := false
+		for _, existing := range repositories {
+			known = known || existing.ID == repository.ID
+		}
+		if !known {
+			repositories = append(repositories, repository)
+		}
+	}
+	registerRepositories(repositories)
+	return repositories
+}
@@ -0,0 +1,408 @@
+package downloader
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"jpkg/jvm"
+	"jpkg/pkg/config"
+	"jpkg/pkg/lock"
+	"jpkg/pkg/store"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// packagingSource marks graph nodes that jpkg builds from source.
+const packagingSource = "source"
+
+// sourceBuild is a dependency that jpkg builds from its sources.
+type sourceBuild struct {
+	// Artifact is the dependency as it appears in the graph,
+	// user:repo:tag for github and path:name:local for path dependencies.
+	Artifact
+	Name   string
+	Origin string
+	// Root holds the sources and Dir the build output, as well as the
+	// unpacked archive for github dependencies.
+	Root string
+	Dir  string
+	// Config is the project's own amber.toml, nil when it has none.
+	Config *config.Config
+	// URL and Hash identify the source archive, or the project directory
+	// and the fingerprint of its sources for path dependencies.
+	URL  string
+	Hash string
+	// Dependencies and Repositories come from the project's amber.toml,
+	// as do the names of the path dependencies in Paths.
+	Dependencies []Artifact
+	Repositories []config.Repository
+	Paths        []string
+	// Nested is set for path dependencies that are only needed by other
+	// path dependencies rather than declared by the project itself.
+	Nested bool
+}
+
+func (s *sourceBuild) fileName() string {
+	return fmt.Sprintf("%s-%s.jar", s.ArtifactID, strings.ReplaceAll(s.Version, "/", "-"))
+}
+
+// readConfig loads the amber.toml of the sources, if there is one, and
+// takes the dependencies a consumer needs from it.
+func (s *sourceBuild) readConfig() error {
+	path := filepath.Join(s.Root, "amber.toml")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	cfg, err := config.ReadTomlConfig(path)
+	if err != nil {
+		return fmt.Errorf("failed to read amber.toml of %s: %w", s.Name, err)
+	}
+	s.Config = cfg
+
+	roots, err := mavenRoots(cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", s.Name, err)
+	}
+	for _, root := range roots {
+		if root.Scope == config.ScopeCompile || root.Scope == config.ScopeRuntime {
+			s.Dependencies = append(s.Dependencies, root)
+		}
+	}
+	for _, name := range cfg.DependencyNames() {
+		dep := cfg.Dependencies[name]
+		switch {
+		case dep.Origin == "maven":
+		case dep.Origin == "path" && s.Origin == "path":
+			s.Paths = append(s.Paths, name)
+			if scope := dep.EffectiveScope(); scope == config.ScopeCompile || scope == config.ScopeRuntime {
+				s.Dependencies = append(s.Dependencies, Artifact{GroupID: "path", ArtifactID: name, Version: localVersion, Scope: scope})
+			}
+		default:
+			downloads.Printf("Warning: %s depends on %s, which is not installed for built projects\n", s.Name, name)
+		}
+	}
+
+	s.Repositories, err = projectRepositories(cfg)
+	return err
+}
+
+// build compiles the sources against classpath and installs the jar at
+// dest. Projects with an amber.toml go through jpkg's own build, anything
+// else has its src tree compiled.
+func (s *sourceBuild) build(classpath []string, dest string) error {
+	fmt.Printf("Building %s\n", s.Name)
+	binDir := filepath.Join(s.Dir, "bin")
+	mainClass, release, srcDir := "", "", "src"
+	if s.Config != nil {
+		mainClass, release, srcDir = s.Config.MainClass, s.Config.Release, s.Config.SourceDir()
+	}
+	if err := jvm.CompileJava(filepath.Join(s.Root, srcDir), binDir, classpath, release); err != nil {
+		return fmt.Errorf("failed to compile %s: %w", s.Name, err)
+	}
+	jarPath, err := jvm.CreateJar(binDir, s.fileName(), mainClass, nil)
+	if err != nil {
+		return fmt.Errorf("failed to package %s: %w", s.Name, err)
+	}
+	if err := os.Rename(jarPath, dest); err != nil {
+		return err
+	}
+	if s.Origin == "path" {
+		return writeStamp(s.fileName(), s.Hash)
+	}
+	return nil
+}
+
+// upToDate reports whether the jar installed from an earlier build was
+// built from the same sources.
+func (s *sourceBuild) upToDate(locked *lock.Artifact) bool {
+	if s.Origin == "path" {
+		return readStamp(s.fileName()) == s.Hash
+	}
+	return locked != nil && locked.Built && locked.Hash == s.Hash
+}
+
+func gitHubArchive(user, repo, tag string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/archive/refs/tags/%s.tar.gz", user, repo, url.PathEscape(tag))
+}
+
+// fetchSources downloads and unpacks the sources of every github
+// dependency of cfg that is built from source and finds the projects of
+// its path dependencies. Path dependencies come before the projects that
+// need them.
+func fetchSources(cfg *config.Config, previous *lock.Lock) ([]*sourceBuild, error) {
+	var sources []*sourceBuild
+	seen := map[string]*sourceBuild{}
+	for _, name := range cfg.DependencyNames() {
+		dep := cfg.Dependencies[name]
+		var found []*sourceBuild
+		var err error
+		switch {
+		case dep.Origin == "github" && dep.Build:
+			var source *sourceBuild
+			if source, err = fetchGitHubSource(name, dep, previous); err == nil {
+				found = []*sourceBuild{source}
+			}
+		case dep.Origin == "path":
+			found, err = pathSources(name, dep, ".", seen)
+		}
+		if err != nil {
+			removeSources(sources)
+			for _, source := range seen {
+				os.RemoveAll(source.Dir)
+			}
+			return nil, err
+		}
+		sources = append(sources, found...)
+	}
+	return sources, nil
+}
+
+func removeSources(sources []*sourceBuild) {
+	for _, source := range sources {
+		os.RemoveAll(source.Dir)
+	}
+}
+
+// fetchGitHubSource downloads and unpacks the source archive of the
+// release dep is pinned to, or of the latest release.
+func fetchGitHubSource(name string, dep config.Dependency, previous *lock.Lock) (*sourceBuild, error) {
+	user, repo, ok := strings.Cut(name, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid github dependency name %q", name)
+	}
+	locked := previous.Artifacts[name]
+
+	tag := dep.Tag
+	if tag == "" && offline() && locked != nil {
+		tag = locked.Version
+	} else if tag == "" {
+		release, err := fetchRelease(user, repo, "")
+		if err != nil {
+			return nil, err
+		}
+		tag = release.TagName
+	}
+
+	source := &sourceBuild{
+		Artifact: Artifact{GroupID: user, ArtifactID: repo, Version: tag, Scope: dep.EffectiveScope()},
+		Name:     name,
+		Origin:   "github",
+		URL:      gitHubArchive(user, repo, tag),
+	}
+	expected := ""
+	if locked != nil && locked.Built && locked.URL == source.URL {
+		expected = locked.Hash
+	}
+
+	var err error
+	if source.Dir, source.Hash, err = fetchSource(name, source.URL, expected); err != nil {
+		return nil, err
+	}
+	source.Root = filepath.Join(source.Dir, "source")
+	if err := source.readConfig(); err != nil {
+		os.RemoveAll(source.Dir)
+		return nil, err
+	}
+	return source, nil
+}
+
+// fetchSource unpacks the archive at archiveURL into a new temporary
+// directory and returns it together with the hash of the archive. The
+// archive is taken from the store when possible and has to match expected
+// unless that is empty.
+func fetchSource(name, archiveURL, expected string) (string, string, error) {
+	dir, err := os.MkdirTemp("", "jpkg-build-*")
+	if err != nil {
+		return "", "", err
+	}
+	hash, err := unpackSource(name, archiveURL, expected, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", err
+	}
+	return dir, hash, nil
+}
+
+func unpackSource(name, archiveURL, expected, dir string) (string, error) {
+	archive := filepath.Join(dir, "source.tar.gz")
+	key := urlStoreKey(archiveURL)
+	stored, fromStore := linkFromStore(key, archive)
+	if !fromStore {
+		if err := downloadFile(name, archiveURL, archive); err != nil {
+			return "", err
+		}
+	}
+
+	var hash string
+	if fromStore {
+		if err := verifyFile(archive, stored.Hash); err != nil {
+			return "", fmt.Errorf("stored sources of %s are corrupt: %w", name, err)
+		}
+		hash = stored.Hash
+	} else {
+		sum, err := fileChecksum(archive, "sha256")
+		if err != nil {
+			return "", err
+		}
+		hash = "sha256:" + sum
+	}
+	// GitHub publishes no checksums, so the first download is trusted and
+	// every later one has to match it.
+	if expected != "" {
+		if err := verifyFile(archive, expected); err != nil {
+			return "", fmt.Errorf("sources of %s differ from the locked ones: %w", name, err)
+		}
+	}
+	if !fromStore {
+		addToStore(key, archive, store.Entry{Hash: hash, Repository: "github", URL: archiveURL})
+	}
+
+	if err := extractTarball(archive, filepath.Join(dir, "source")); err != nil {
+		return "", fmt.Errorf("failed to unpack sources of %s: %w", name, err)
+	}
+	return hash, nil
+}
+
+// extractTarball unpacks a gzipped tarball into dest, dropping the top
+// level directory GitHub wraps the sources in.
+func extractTarball(archive, dest string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, rel, ok := strings.Cut(filepath.ToSlash(header.Name), "/")
+		if !ok || rel == "" {
+			continue
+		}
+		rel = filepath.Clean(filepath.FromSlash(rel))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path %q in archive", header.Name)
+		}
+		path := filepath.Join(dest, rel)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, reader)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// installSource builds the source dependency unless the jar built from
+// the same sources is still installed, and returns its lock entry.
+func installSource(source *sourceBuild, graph *Graph, artifacts map[string]*lock.Artifact, libDir string, previous *lock.Lock) (*lock.Artifact, error) {
+	node := graph.Nodes[source.Key()]
+	entry := &lock.Artifact{
+		GroupID:    source.GroupID,
+		ArtifactID: source.ArtifactID,
+		Version:    source.Version,
+		Origin:     source.Origin,
+		Scope:      node.Scope,
+		File:       source.fileName(),
+		URL:        source.URL,
+		Built:      true,
+	}
+	// The fingerprint of local sources changes with every edit and is not
+	// worth locking
+	if source.Origin == "github" {
+		entry.Repository = "github"
+		entry.Hash = source.Hash
+	}
+	entry.Dependencies, entry.Excluded = graph.lockEdges(node)
+
+	locked := previous.Artifacts[entry.Key()]
+	if locked != nil && locked.File != entry.File {
+		os.Remove(filepath.Join(libDir, locked.File))
+		locked = nil
+	}
+	dest := filepath.Join(libDir, entry.File)
+	if _, err := os.Stat(dest); err == nil && source.upToDate(locked) {
+		return entry, nil
+	}
+
+	var classpath []string
+	for _, key := range graph.Transitive(source.Key()) {
+		if a, ok := artifacts[key]; ok {
+			classpath = append(classpath, filepath.Join(libDir, a.File))
+		}
+	}
+	if err := source.build(classpath, dest); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// rebuildLocked builds a locked source dependency again, from exactly the
+// locked sources for github dependencies and from the project directory
+// for path dependencies.
+func rebuildLocked(l *lock.Lock, a *lock.Artifact, libDir string) error {
+	source := &sourceBuild{
+		Artifact: Artifact{GroupID: a.GroupID, ArtifactID: a.ArtifactID, Version: a.Version},
+		Name:     a.Key(),
+		Origin:   a.Origin,
+	}
+
+	var err error
+	if a.Origin == "path" {
+		source.Name = a.ArtifactID
+		source.Root = filepath.FromSlash(a.URL)
+		if source.Hash, err = fingerprint(source.Root); err != nil {
+			return err
+		}
+		source.Dir, err = os.MkdirTemp("", "jpkg-build-*")
+	} else {
+		source.Dir, _, err = fetchSource(a.Key(), a.URL, a.Hash)
+		source.Root = filepath.Join(source.Dir, "source")
+	}
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(source.Dir)
+
+	if err := source.readConfig(); err != nil {
+		return err
+	}
+
+	var classpath []string
+	for _, dep := range l.Transitive(a.Key()) {
+		classpath = append(classpath, filepath.Join(libDir, dep.File))
+	}
+	return source.build(classpath, filepath.Join(libDir, a.File))
+}
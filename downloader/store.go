@@ -0,0 +1,101 @@
+package downloader
+
+import (
+	"bytes"
+	"io"
+	"jpkg/pkg/config"
+	"jpkg/pkg/store"
+	"os"
+	"strings"
+)
+
+func mavenStoreKey(path string) string {
+	return "maven/" + path
+}
+
+func urlStoreKey(url string) string {
+	for _, scheme := range []string{"https://", "http://", "file://"} {
+		url = strings.TrimPrefix(url, scheme)
+	}
+	return "url/" + url
+}
+
+// Snapshots are republished under the same coordinates and must not be
+// served from the store.
+func cacheable(version string) bool {
+	return !strings.HasSuffix(version, "-SNAPSHOT")
+}
+
+// linkFromStore installs the file stored under key at dest. It reports
+// false when the store does not have it.
+func linkFromStore(key, dest string) (*store.Entry, bool) {
+	entry, ok := store.Lookup(key)
+	if !ok {
+		return nil, false
+	}
+	os.Remove(dest)
+	if err := store.Link(entry, dest); err != nil {
+		downloads.Printf("Failed to use the artifact store: %v\n", err)
+		return nil, false
+	}
+	return entry, true
+}
+
+// addToStore keeps a copy of a verified file in the store. Failing to do
+// so only costs a download later, so it is not treated as an error.
+func addToStore(key, path string, entry store.Entry) {
+	if _, err := store.AddFile(key, path, entry); err != nil {
+		downloads.Printf("Failed to add to the artifact store: %v\n", err)
+	}
+}
+
+// storedPOM returns the pom of a from the store, or fetches it from the
+// first repository that has it, verifies it against the checksum that
+// repository publishes and stores it.
+func storedPOM(repositories []config.Repository, a Artifact) (io.ReadCloser, error) {
+	path := a.path("pom")
+	key := mavenStoreKey(path)
+	if cacheable(a.Version) {
+		// Poms stored before they were verified are fetched again
+		if entry, ok := store.Lookup(key); ok && entry.Hash != "" {
+			if file, err := store.Open(entry); err == nil {
+				if err := verifyFile(file.Name(), entry.Hash); err == nil {
+					return file, nil
+				}
+				file.Close()
+			}
+		}
+	}
+
+	body, _, repository, err := fetchArtifact(repositories, path)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp("", "jpkg-*.pom")
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, body)
+	body.Close()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := verifyDownload(repository, path, tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	if cacheable(a.Version) {
+		addToStore(key, tmp.Name(), store.Entry{Hash: hash, Repository: repository.ID, URL: repository.URL + "/" + path})
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
@@ -0,0 +1,87 @@
+package downloader
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"jpkg/pkg/config"
+	"jpkg/pkg/store"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPOMRepository publishes a pom of g:a:1 with the given content and a
+// sha1 sidecar of published, and returns the repository.
+func testPOMRepository(t *testing.T, content, published string) config.Repository {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "g", "a", "1", "a-1.pom")
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum([]byte(published))
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".sha1", []byte(hex.EncodeToString(sum[:])+"  a-1.pom\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return config.Repository{ID: "test", URL: "file://" + filepath.ToSlash(dir)}
+}
+
+func fetchTestPOM(t *testing.T, repository config.Repository) (string, error) {
+	t.Helper()
+	body, err := pomFetcher([]config.Repository{repository})("g", "a", "1")
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	content, err := io.ReadAll(body)
+	return string(content), err
+}
+
+const testPOM = "<project><groupId>g</groupId><artifactId>a</artifactId><version>1</version></project>"
+
+func TestStoredPOMVerified(t *testing.T) {
+	useSettings(t, "")
+	repository := testPOMRepository(t, testPOM, testPOM)
+
+	// A pom stored before poms were verified is not trusted
+	if _, err := store.Add(mavenStoreKey("g/a/1/a-1.pom"), strings.NewReader("<project/>"), store.Entry{}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := fetchTestPOM(t, repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != testPOM {
+		t.Errorf("pom = %q", content)
+	}
+
+	entry, ok := store.Lookup(mavenStoreKey("g/a/1/a-1.pom"))
+	sum := sha1.Sum([]byte(testPOM))
+	if !ok || entry.Hash != "sha1:"+hex.EncodeToString(sum[:]) || entry.Repository != "test" {
+		t.Fatalf("stored entry %+v", entry)
+	}
+
+	// Later fetches come from the store, which checks its copy
+	os.RemoveAll(strings.TrimPrefix(repository.URL, "file://"))
+	if content, err := fetchTestPOM(t, repository); err != nil || content != testPOM {
+		t.Errorf("stored pom = %q, %v", content, err)
+	}
+}
+
+func TestTamperedPOM(t *testing.T) {
+	useSettings(t, "")
+	tampered := strings.Replace(testPOM, "</project>", "<dependencies><dependency><groupId>evil</groupId><artifactId>evil</artifactId><version>1</version></dependency></dependencies></project>", 1)
+	repository := testPOMRepository(t, tampered, testPOM)
+
+	if _, err := fetchTestPOM(t, repository); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("tampered pom error = %v", err)
+	}
+	if _, ok := store.Lookup(mavenStoreKey("g/a/1/a-1.pom")); ok {
+		t.Error("tampered pom was stored")
+	}
+}
@@ -0,0 +1,52 @@
+plugins {
+    application
+}
+
+group = "org.demo"
+version = "1.2.0"
+description = "Demo \"app\" for \$USER"
+
+repositories {
+    maven {
+        name = "company"
+        url = uri("https://repo.example.com/maven")
+    }
+    mavenCentral()
+}
+
+tasks.withType<JavaCompile>().configureEach {
+    options.release.set(17)
+}
+
+sourceSets {
+    main {
+        java.setSrcDirs(listOf("src/main/java"))
+        resources.setSrcDirs(listOf("src/main/resources"))
+    }
+}
+
+configurations.all {
+    resolutionStrategy {
+        force("com.ex:b:2.0")
+        force("org.slf4j:slf4j-api:2.0.9")
+    }
+}
+
+dependencies {
+    implementation("com.google.guava:guava:33.0.0-jre") {
+        exclude(group = "com.google.code.findbugs", module = "jsr305")
+        exclude(module = "checker-qual")
+        exclude(group = "com.google.errorprone")
+    }
+    runtimeOnly("org.demo:natives:1.0:natives-linux")
+    implementation("org.demo:assets:1.0@zip")
+    testImplementation("org.demo:fixtures:1.0:tests")
+    compileOnly("org.projectlombok:lombok:1.18.30")
+    testImplementation("junit:junit:4.13.2") {
+        isTransitive = false
+    }
+}
+
+application {
+    mainClass.set("org.demo.Main")
+}
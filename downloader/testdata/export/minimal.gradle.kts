@@ -0,0 +1,20 @@
+plugins {
+    java
+}
+
+group = "downloader"
+version = "1.0-SNAPSHOT"
+
+repositories {
+    mavenCentral()
+}
+
+sourceSets {
+    main {
+        java.setSrcDirs(listOf("src"))
+        resources.setSrcDirs(listOf("resources"))
+    }
+}
+
+dependencies {
+}
@@ -0,0 +1,46 @@
+plugins {
+    application
+    id("com.github.johnrengelman.shadow") version "8.1.1"
+}
+
+group = "org.demo"
+version = "0.3"
+
+val nativesVersion = "1.0"
+
+repositories {
+    mavenCentral()
+    maven {
+        name = "company"
+        url = uri("https://repo.example.com/releases/") // releases only
+    }
+}
+
+java {
+    toolchain {
+        languageVersion.set(JavaLanguageVersion.of(21))
+    }
+}
+
+configurations.all {
+    resolutionStrategy {
+        force("org.slf4j:slf4j-api:2.0.9")
+    }
+}
+
+dependencies {
+    implementation("com.google.guava:guava:33.0.0-jre") {
+        exclude(group = "com.google.code.findbugs", module = "jsr305")
+    }
+    runtimeOnly("org.demo:natives:$nativesVersion:natives-linux")
+    implementation(libs.jackson.databind)
+    implementation(libs.commons.lang)
+    testImplementation(libs.bundles.testing)
+    compileOnly(group = "org.projectlombok", name = "lombok", version = "1.18.30")
+    implementation(project(":shared"))
+    annotationProcessor("org.projectlombok:lombok:1.18.30")
+}
+
+application {
+    mainClass.set("org.demo.Main")
+}
@@ -0,0 +1,153 @@
+package downloader
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"jpkg/pkg/config"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// newTransport builds the transport of httpClient from the [http] user
+// settings.
+func newTransport(settings config.HTTP) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	proxy, err := proxyFunc(settings)
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy = proxy
+
+	tlsConfig, err := tlsConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// proxyFunc picks the proxy of a request. Without a configured proxy the
+// usual environment variables apply.
+func proxyFunc(settings config.HTTP) (func(*http.Request) (*url.URL, error), error) {
+	rawURL, err := settings.ProxyURL()
+	if err != nil {
+		return nil, err
+	}
+	if rawURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url %q", settings.Proxy)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", proxyURL.Scheme)
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL, settings.NoProxy) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// defaultPorts are the ports of requests whose url has none.
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// bypassProxy reports whether the host of u matches one of the no_proxy
+// entries.
+func bypassProxy(u *url.URL, noProxy []string) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[u.Scheme]
+	}
+	ip := net.ParseIP(host)
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		// host:port and [ipv6]:port only match that port
+		if entryHost, entryPort, err := net.SplitHostPort(entry); err == nil {
+			if entryPort != port {
+				continue
+			}
+			entry = entryHost
+		}
+		entry = strings.TrimSuffix(strings.TrimPrefix(entry, "["), "]")
+		switch {
+		case entry == "":
+		case entry == "*":
+			return true
+		case strings.Contains(entry, "/"):
+			if _, network, err := net.ParseCIDR(entry); err == nil && ip != nil && network.Contains(ip) {
+				return true
+			}
+		case ip != nil:
+			if entryIP := net.ParseIP(entry); entryIP != nil && entryIP.Equal(ip) {
+				return true
+			}
+		default:
+			domain := strings.TrimPrefix(entry, ".")
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tlsConfig adds the extra CA bundles and the client certificate to the
+// default TLS settings.
+func tlsConfig(settings config.HTTP) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if len(settings.CAFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, name := range settings.CAFiles {
+			path, err := config.SettingsFile(name)
+			if err != nil {
+				return nil, err
+			}
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("%s holds no PEM certificates", path)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if settings.ClientCertificate != "" {
+		certFile, err := config.SettingsFile(settings.ClientCertificate)
+		if err != nil {
+			return nil, err
+		}
+		keyFile := certFile
+		if settings.ClientKey != "" {
+			if keyFile, err = config.SettingsFile(settings.ClientKey); err != nil {
+				return nil, err
+			}
+		}
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	} else if settings.ClientKey != "" {
+		return nil, fmt.Errorf("client_key is set without client_certificate")
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,163 @@
+package downloader
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"jpkg/pkg/config"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBypassProxy(t *testing.T) {
+	noProxy := []string{"localhost", ".internal.example.com", "example.org", "10.0.0.0/8", "fd00::/8", "192.168.1.7", "::1", " Build.Example.NET ", "repo.example.com:8443", "[2001:db8::2]:8080", "172.16.0.1:80"}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"http://localhost:8080/a", true},
+		{"https://internal.example.com/a", true},
+		{"https://repo.internal.example.com/a", true},
+		{"https://example.org/a", true},
+		{"https://repo.example.org/a", true},
+		{"https://badexample.org/a", false},
+		{"https://example.org.evil.com/a", false},
+		{"http://10.1.2.3/a", true},
+		{"http://11.1.2.3/a", false},
+		{"http://[fd00::5]/a", true},
+		{"http://192.168.1.7:8081/a", true},
+		{"http://192.168.1.8/a", false},
+		{"http://[::1]:8080/a", true},
+		{"https://build.example.net/a", true},
+		// entries with a port only match that port
+		{"https://repo.example.com:8443/a", true},
+		{"https://repo.example.com/a", false},
+		{"http://[2001:db8::2]:8080/a", true},
+		{"http://[2001:db8::2]/a", false},
+		{"http://172.16.0.1/a", true},
+		{"https://172.16.0.1/a", false},
+		{"https://repo1.maven.org/maven2", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bypassProxy(u, noProxy); got != tt.want {
+			t.Errorf("bypassProxy(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	u, _ := url.Parse("https://anything.example.com")
+	if !bypassProxy(u, []string{"", "*"}) {
+		t.Error("* does not match every host")
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	proxy, err := proxyFunc(config.HTTP{Proxy: "http://proxy.example.com:3128", NoProxy: []string{"internal.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for target, want := range map[string]string{
+		"https://repo1.maven.org/maven2":    "http://proxy.example.com:3128",
+		"https://internal.example.com/repo": "",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, target, nil)
+		got, err := proxy(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (got == nil && want != "") || (got != nil && got.String() != want) {
+			t.Errorf("proxy of %s = %v, want %q", target, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"ftp://proxy.example.com", "proxy.example.com:3128"} {
+		if _, err := proxyFunc(config.HTTP{Proxy: invalid}); err == nil {
+			t.Errorf("proxy %q was accepted", invalid)
+		}
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM
+// files to dir.
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "jpkg test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("AMBER_HOME", home)
+	certFile, keyFile := writeTestCertificate(t, home)
+	notPEM := filepath.Join(home, "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Relative names are taken from the directory of settings.toml
+	loaded, err := tlsConfig(config.HTTP{CAFiles: []string{"client.pem"}, ClientCertificate: "client.pem", ClientKey: "client.key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.RootCAs == nil || len(loaded.Certificates) != 1 {
+		t.Errorf("CA bundle or client certificate missing from %+v", loaded)
+	}
+
+	tests := []struct {
+		name     string
+		settings config.HTTP
+		err      string
+	}{
+		{"key without certificate", config.HTTP{ClientKey: keyFile}, "client_key is set without client_certificate"},
+		{"certificate without its key", config.HTTP{ClientCertificate: certFile}, "failed to load client certificate"},
+		{"missing CA bundle", config.HTTP{CAFiles: []string{"missing.pem"}}, "failed to read CA bundle"},
+		{"CA bundle without certificates", config.HTTP{CAFiles: []string{notPEM}}, "holds no PEM certificates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tlsConfig(tt.settings)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("tlsConfig error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
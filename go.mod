@@ -3,12 +3,8 @@ module jpkg
 go 1.22.5
 
 require (
-	github.com/BurntSushi/toml v1.4.0 // indirect
-	github.com/hedzr/progressbar v1.1.8 // indirect
-	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
-	github.com/rivo/uniseg v0.4.7 // indirect
-	github.com/schollz/progressbar/v3 v3.14.6 // indirect
-	golang.org/x/net v0.23.0 // indirect
-	golang.org/x/sys v0.22.0 // indirect
-	golang.org/x/term v0.22.0 // indirect
+	github.com/BurntSushi/toml v1.4.0
+	golang.org/x/term v0.22.0
 )
+
+require golang.org/x/sys v0.22.0 // indirect
@@ -1,7 +1,6 @@
 package jvm
 
 import (
-	"errors"
 	"fmt"
 	"jpkg/pkg/cache"
 	"os"
@@ -46,22 +45,14 @@ func copyDir(src string, dest string) error {
 	})
 }
 
-func CompileJava(srcDir, binDir, libDir string) error {
+// CompileJava compiles the sources in srcDir into binDir, for the given
+// Java release unless it is empty.
+func CompileJava(srcDir, binDir string, classpath []string, release string) error {
 	javaFiles, err := getJavaFiles(srcDir)
 	if err != nil {
 		return err
 	}
 
-	var jarFiles string
-
-	// Check if the lib directory exists
-	if _, err := os.Stat(libDir); err == nil {
-		jarFiles, err = getJarFiles(libDir)
-		if err != nil {
-			return err
-		}
-	}
-
 	// Ensure the binDir and cacheDir directories exist
 	if _, err := os.Stat(binDir); os.IsNotExist(err) {
 		err := os.MkdirAll(binDir, os.ModePerm)
@@ -81,8 +72,11 @@ func CompileJava(srcDir, binDir, libDir string) error {
 
 	// Construct javac arguments
 	var args []string
-	if jarFiles != "" {
-		args = append(args, "-cp", jarFiles)
+	if len(classpath) > 0 {
+		args = append(args, "-cp", strings.Join(classpath, string(os.PathListSeparator)))
+	}
+	if release != "" {
+		args = append(args, "--release", release)
 	}
 	args = append(args, "-d", binDir)
 	args = append(args, javaFiles...)
@@ -95,8 +89,8 @@ func CompileJava(srcDir, binDir, libDir string) error {
 		return err
 	}
 
-	// Copy resources to binDir
-	resourcesDir := "resources"
+	// Copy resources, which live next to the sources, to binDir
+	resourcesDir := filepath.Join(filepath.Dir(srcDir), "resources")
 	if _, err := os.Stat(resourcesDir); !os.IsNotExist(err) {
 		err = copyDir(resourcesDir, binDir)
 		if err != nil {
@@ -107,7 +101,7 @@ func CompileJava(srcDir, binDir, libDir string) error {
 	return nil
 }
 
-func CreateJar(binDir, jarFileName, mainClass, libDir string) (string, error) {
+func CreateJar(binDir, jarFileName, mainClass string, classpath []string) (string, error) {
 	buildDir := filepath.Join(".jpkg", "build", "jar")
 	if _, err := os.Stat(buildDir); os.IsNotExist(err) {
 		if err := os.MkdirAll(buildDir, os.ModePerm); err != nil {
@@ -115,21 +109,20 @@ func CreateJar(binDir, jarFileName, mainClass, libDir string) (string, error) {
 		}
 	}
 
-	jarFiles, err := getJarFiles(libDir)
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		return "", err
-	}
-
-	relJarFiles := strings.ReplaceAll(jarFiles, string(os.PathSeparator), "/")
-	relJarFilesList := strings.Split(relJarFiles, string(os.PathListSeparator))
-
-	for index, file := range relJarFilesList {
+	relJarFilesList := make([]string, len(classpath))
+	for index, file := range classpath {
 		absJarPath, _ := filepath.Abs(file)
-		relJarFilesList[index] = absJarPath
+		relJarFilesList[index] = filepath.ToSlash(absJarPath)
 	}
 
 	manifestFile := filepath.Join(binDir, "MANIFEST.MF")
-	manifestContent := fmt.Sprintf("Main-Class: %s\nClass-Path: %s\n", mainClass, strings.Join(relJarFilesList, " "))
+	manifestContent := ""
+	if mainClass != "" {
+		manifestContent += fmt.Sprintf("Main-Class: %s\n", mainClass)
+	}
+	if len(relJarFilesList) > 0 {
+		manifestContent += fmt.Sprintf("Class-Path: %s\n", strings.Join(relJarFilesList, " "))
+	}
 	if err := os.WriteFile(manifestFile, []byte(manifestContent), 0644); err != nil {
 		return "", err
 	}
@@ -147,7 +140,53 @@ func CreateJar(binDir, jarFileName, mainClass, libDir string) (string, error) {
 	return jarFilePath, nil
 }
 
-func BuildNative(jarPath string, args []string) error {
+// CreateSourcesJar packs the sources and resources of the project into a
+// jar, as published next to the main one.
+func CreateSourcesJar(srcDir, jarFileName string) (string, error) {
+	stageDir := filepath.Join(".jpkg", "build", "sources")
+	if err := os.RemoveAll(stageDir); err != nil {
+		return "", err
+	}
+	if err := copyDir(srcDir, stageDir); err != nil {
+		return "", fmt.Errorf("failed to copy sources: %w", err)
+	}
+	resourcesDir := filepath.Join(filepath.Dir(srcDir), "resources")
+	if _, err := os.Stat(resourcesDir); !os.IsNotExist(err) {
+		if err := copyDir(resourcesDir, stageDir); err != nil {
+			return "", fmt.Errorf("failed to copy resources: %w", err)
+		}
+	}
+	return CreateJar(stageDir, jarFileName, "", nil)
+}
+
+// CreateJavadocJar generates the API documentation of the sources and
+// packs it into a jar.
+func CreateJavadocJar(srcDir, jarFileName string, classpath []string) (string, error) {
+	javaFiles, err := getJavaFiles(srcDir)
+	if err != nil {
+		return "", err
+	}
+	docDir := filepath.Join(".jpkg", "build", "javadoc")
+	if err := os.RemoveAll(docDir); err != nil {
+		return "", err
+	}
+
+	args := []string{"-quiet", "-Xdoclint:none", "-d", docDir}
+	if len(classpath) > 0 {
+		args = append(args, "-cp", strings.Join(classpath, string(os.PathListSeparator)))
+	}
+	args = append(args, javaFiles...)
+
+	cmd := exec.Command("javadoc", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to generate javadoc: %w", err)
+	}
+	return CreateJar(docDir, jarFileName, "", nil)
+}
+
+func BuildNative(jarPath string, classpath []string, args []string) error {
 	buildDir := filepath.Join(".jpkg", "build", "linux")
 	if _, err := os.Stat(buildDir); os.IsNotExist(err) {
 		if err := os.MkdirAll(buildDir, os.ModePerm); err != nil {
@@ -155,6 +194,9 @@ func BuildNative(jarPath string, args []string) error {
 		}
 	}
 	command := []string{"native-image", "--no-fallback"}
+	if len(classpath) > 0 {
+		command = append(command, "-cp", strings.Join(classpath, string(os.PathListSeparator)))
+	}
 	command = append(command, args...)
 
 	cmd := exec.Command(command[0], append(command[1:], "-jar", jarPath, ".jpkg/build/linux/app")...)
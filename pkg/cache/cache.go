@@ -57,12 +57,13 @@ func IsCacheUpToDate(srcDir, cacheDir string) (bool, error) {
 	resourcesFiles := map[string]string{}
 	cacheFiles := map[string]string{}
 
-	err := filepath.Walk("resources", func(path string, info fs.FileInfo, err error) error {
+	resourcesDir := filepath.Join(filepath.Dir(srcDir), "resources")
+	err := filepath.Walk(resourcesDir, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if !info.IsDir() {
-			relPath, _ := filepath.Rel("resources", path)
+			relPath, _ := filepath.Rel(resourcesDir, path)
 			hash, err := computeFileHash(path)
 			if err != nil {
 				return err
@@ -156,12 +157,13 @@ func CopySrcToCache(srcDir, cacheDir string) error {
 		return err
 	}
 
-	err = filepath.Walk("resources", func(path string, info os.FileInfo, err error) error {
+	resourcesDir := filepath.Join(filepath.Dir(srcDir), "resources")
+	err = filepath.Walk(resourcesDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if !info.IsDir() {
-			relPath, _ := filepath.Rel("resources", path)
+			relPath, _ := filepath.Rel(resourcesDir, path)
 			destPath := filepath.Join(cacheDir, relPath)
 			destDir := filepath.Dir(destPath)
 			if _, err := os.Stat(destDir); os.IsNotExist(err) {
@@ -4,30 +4,294 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/BurntSushi/toml"
 )
 
 type Config struct {
-	MainClass    string `toml:"main_class"`
+	MainClass string `toml:"main_class"`
+	// Release is the Java version the sources are compiled for, passed to
+	// javac --release.
+	Release string `toml:"release,omitempty"`
+	// SrcDir holds the sources when they are not in src, with the
+	// resources in the resources directory next to it.
+	SrcDir string `toml:"src_dir,omitempty"`
+	// Group, Name and Version are the coordinates the project is
+	// published under, described by the other metadata in its POM.
+	Group        string `toml:"group,omitempty"`
+	Name         string `toml:"name,omitempty"`
+	Version      string `toml:"version,omitempty"`
+	Description  string `toml:"description,omitempty"`
+	URL          string `toml:"url,omitempty"`
+	License      string `toml:"license,omitempty"`
 	Dependencies map[string]Dependency
+	Resolution   Resolution        `toml:"resolution,omitempty"`
+	Repositories map[string]string `toml:"repositories,omitempty"`
+	// Overrides forces the version of a groupId:artifactId wherever it
+	// appears in the dependency graph.
+	Overrides map[string]string `toml:"overrides,omitempty"`
+	Publish   Publish           `toml:"publish,omitempty"`
+
+	dependencyOrder []string
+	repositoryOrder []string
+}
+
+const (
+	CentralID  = "central"
+	CentralURL = "https://repo1.maven.org/maven2"
+)
+
+// Repository is a Maven layout repository, served over http(s) or from a
+// file:// directory.
+type Repository struct {
+	ID  string
+	URL string
+	// Mirror is the [mirrors] key of the user settings whose url replaced
+	// the declared one, empty when the repository is not mirrored.
+	Mirror string
 }
 
 type Dependency struct {
-	Origin  string
-	Version string
+	Origin  string `json:"origin"`
+	Version string `json:"version,omitempty"`
+	// Tag pins a github dependency to a release, the latest release is
+	// used when it is empty.
+	Tag string `toml:"tag,omitempty" json:"tag,omitempty"`
+	// Asset selects the jar of a github release by name, either as a glob
+	// such as "*-all.jar" or as a regular expression written as /.../.
+	Asset string `toml:"asset,omitempty" json:"asset,omitempty"`
+	// Build compiles a github dependency from the source archive of its
+	// release instead of downloading a jar.
+	Build bool `toml:"build,omitempty" json:"build,omitempty"`
+	// Path is the directory of another jpkg project, relative to this
+	// one, for dependencies with the "path" origin.
+	Path string `toml:"path,omitempty" json:"path,omitempty"`
+	// Classifier and Type select another file of a maven artifact than its
+	// main jar, such as natives-linux jars or zip distributions.
+	Classifier string `toml:"classifier,omitempty" json:"classifier,omitempty"`
+	Type       string `toml:"type,omitempty" json:"type,omitempty"`
+	// Exclude drops groupId:artifactId coordinates, * matching any group or
+	// artifact, from the transitive dependencies of a maven dependency.
+	Exclude []string `toml:"exclude,omitempty" json:"exclude,omitempty"`
+	Scope   string   `toml:"scope,omitempty" json:"scope,omitempty"`
+}
+
+const (
+	ScopeCompile  = "compile"
+	ScopeRuntime  = "runtime"
+	ScopeProvided = "provided"
+	ScopeTest     = "test"
+)
+
+// EffectiveScope returns the scope of the dependency, compile when none
+// is set.
+func (d Dependency) EffectiveScope() string {
+	if d.Scope == "" {
+		return ScopeCompile
+	}
+	return d.Scope
+}
+
+// SplitMavenName returns the groupId and artifactId of a maven dependency
+// named group/artifact. Several classifiers of one artifact are told apart
+// by a :classifier suffix on the name, which is ignored here.
+func SplitMavenName(name string) (string, string, error) {
+	name, _, _ = strings.Cut(name, ":")
+	parts := strings.Split(name, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid maven dependency name %q", name)
+	}
+	return parts[0], parts[1], nil
+}
+
+// Maven dependency types whose files are jars, and the classifiers some of
+// them imply. Any other type is the extension of its files.
+var (
+	jarTypes        = map[string]bool{"": true, "jar": true, "bundle": true, "ejb": true, "maven-plugin": true, "test-jar": true, "ejb-client": true, "java-source": true, "javadoc": true}
+	typeClassifiers = map[string]string{"test-jar": "tests", "ejb-client": "client", "java-source": "sources", "javadoc": "javadoc"}
+)
+
+// TypeExtension returns the file extension of a maven dependency type.
+func TypeExtension(typ string) string {
+	if jarTypes[typ] {
+		return "jar"
+	}
+	return typ
+}
+
+// TypeClassifier returns the classifier of a maven dependency, which is
+// implied by some types when none is given.
+func TypeClassifier(typ, classifier string) string {
+	if classifier != "" {
+		return classifier
+	}
+	return typeClassifiers[typ]
+}
+
+// ValidateCoordinate checks that value is a groupId:artifactId, where
+// wildcard tells whether either of them may be *.
+func ValidateCoordinate(value string, wildcard bool) error {
+	groupID, artifactID, ok := strings.Cut(value, ":")
+	if !ok || groupID == "" || artifactID == "" || strings.Contains(artifactID, ":") {
+		return fmt.Errorf("invalid coordinate %q, use groupId:artifactId", value)
+	}
+	if !wildcard && (groupID == "*" || artifactID == "*") {
+		return fmt.Errorf("invalid coordinate %q, wildcards are not allowed here", value)
+	}
+	return nil
+}
+
+func ValidateScope(scope string) error {
+	switch scope {
+	case "", ScopeCompile, ScopeRuntime, ScopeProvided, ScopeTest:
+		return nil
+	default:
+		return fmt.Errorf("unknown scope %q, use compile, runtime, provided or test", scope)
+	}
+}
+
+const (
+	StrategyNearest = "nearest"
+	StrategyHighest = "highest"
+)
+
+// Resolution controls how version conflicts between transitive
+// dependencies are settled.
+type Resolution struct {
+	Strategy string `toml:"strategy,omitempty"`
+}
+
+// ConflictStrategy returns the configured strategy, defaulting to Maven's
+// nearest-wins behaviour.
+func (c *Config) ConflictStrategy() (string, error) {
+	switch c.Resolution.Strategy {
+	case "", StrategyNearest:
+		return StrategyNearest, nil
+	case StrategyHighest:
+		return StrategyHighest, nil
+	default:
+		return "", fmt.Errorf("unknown resolution strategy %q, use %q or %q", c.Resolution.Strategy, StrategyNearest, StrategyHighest)
+	}
+}
+
+// VersionOverrides returns the forced versions keyed by
+// groupId:artifactId.
+func (c *Config) VersionOverrides() (map[string]string, error) {
+	for coordinate, version := range c.Overrides {
+		if err := ValidateCoordinate(coordinate, false); err != nil {
+			return nil, fmt.Errorf("[overrides]: %w", err)
+		}
+		if version == "" {
+			return nil, fmt.Errorf("[overrides]: %s has no version", coordinate)
+		}
+	}
+	return c.Overrides, nil
+}
+
+// SourceDir returns the directory of the project's sources.
+func (c *Config) SourceDir() string {
+	if c.SrcDir == "" {
+		return "src"
+	}
+	return filepath.Clean(c.SrcDir)
+}
+
+// Publish configures jpkg publish.
+type Publish struct {
+	// Repository is the id of a [repositories] entry, or a url, the
+	// project is uploaded to.
+	Repository string `toml:"repository,omitempty"`
+}
+
+// Coordinates returns the groupId, artifactId and version the project is
+// published under.
+func (c *Config) Coordinates() (string, string, string, error) {
+	var missing []string
+	for _, field := range []struct{ key, value string }{{"group", c.Group}, {"name", c.Name}, {"version", c.Version}} {
+		if field.value == "" {
+			missing = append(missing, field.key)
+		}
+	}
+	if len(missing) > 0 {
+		return "", "", "", fmt.Errorf("set %s in amber.toml to publish", strings.Join(missing, ", "))
+	}
+	return c.Group, c.Name, c.Version, nil
+}
+
+// MavenRepositories returns the repositories declared in amber.toml in
+// declaration order. Maven Central is always searched last unless a
+// repository with the id "central" is declared.
+func (c *Config) MavenRepositories() []Repository {
+	var repositories []Repository
+	hasCentral := false
+	for _, id := range c.repositoryOrder {
+		url, ok := c.Repositories[id]
+		if !ok {
+			continue
+		}
+		repositories = append(repositories, Repository{ID: id, URL: strings.TrimSuffix(url, "/")})
+		if id == CentralID {
+			hasCentral = true
+		}
+	}
+	if !hasCentral {
+		repositories = append(repositories, Repository{ID: CentralID, URL: CentralURL})
+	}
+	return repositories
+}
+
+// DependencyNames returns the dependency names in the order they are
+// declared in amber.toml.
+func (c *Config) DependencyNames() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range c.dependencyOrder {
+		if _, ok := c.Dependencies[name]; ok && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+
+	var rest []string
+	for name := range c.Dependencies {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
 }
 
 func GetTomlConfig() (*Config, error) {
-	var config Config
 	if _, err := os.Stat("amber.toml"); os.IsNotExist(err) {
 		return nil, fmt.Errorf("amber.toml file not found")
 	}
-	if _, err := toml.DecodeFile("amber.toml", &config); err != nil {
+	return ReadTomlConfig("amber.toml")
+}
+
+// ReadTomlConfig loads the amber.toml at path, e.g. the one of a
+// dependency that is built from source.
+func ReadTomlConfig(path string) (*Config, error) {
+	var config Config
+	meta, err := toml.DecodeFile(path, &config)
+	if err != nil {
 		return nil, err
 	}
+
+	for _, key := range meta.Keys() {
+		if len(key) != 2 {
+			continue
+		}
+		switch key[0] {
+		case "dependencies":
+			config.dependencyOrder = append(config.dependencyOrder, key[1])
+		case "repositories":
+			config.repositoryOrder = append(config.repositoryOrder, key[1])
+		}
+	}
 	return &config, nil
 }
 
@@ -66,46 +330,190 @@ func CreateInitialFiles() error {
 	return nil
 }
 
-func saveConfig(filename string, dependencies string) error {
-	// Read the existing file content
-	content, err := os.ReadFile("amber.toml")
+// dependencyField is a key of a dependency with its rendered TOML value.
+type dependencyField struct {
+	key, value string
+}
+
+// dependencyKeys lists every key a dependency can have, in the order they
+// are written.
+var dependencyKeys = []string{"origin", "version", "tag", "asset", "build", "path", "classifier", "type", "exclude", "scope"}
+
+// dependencyFields renders the keys of a dependency that are set.
+func dependencyFields(dep Dependency) []dependencyField {
+	fields := []dependencyField{{"origin", strconv.Quote(dep.Origin)}}
+	if dep.Version != "" || dep.Origin == "maven" {
+		fields = append(fields, dependencyField{"version", strconv.Quote(dep.Version)})
+	}
+	if dep.Tag != "" {
+		fields = append(fields, dependencyField{"tag", strconv.Quote(dep.Tag)})
+	}
+	if dep.Asset != "" {
+		fields = append(fields, dependencyField{"asset", strconv.Quote(dep.Asset)})
+	}
+	if dep.Build {
+		fields = append(fields, dependencyField{"build", "true"})
+	}
+	if dep.Path != "" {
+		fields = append(fields, dependencyField{"path", strconv.Quote(dep.Path)})
+	}
+	if dep.Classifier != "" {
+		fields = append(fields, dependencyField{"classifier", strconv.Quote(dep.Classifier)})
+	}
+	if dep.Type != "" {
+		fields = append(fields, dependencyField{"type", strconv.Quote(dep.Type)})
+	}
+	if len(dep.Exclude) > 0 {
+		var excluded []string
+		for _, coordinate := range dep.Exclude {
+			excluded = append(excluded, strconv.Quote(coordinate))
+		}
+		fields = append(fields, dependencyField{"exclude", "[" + strings.Join(excluded, ", ") + "]"})
+	}
+	if dep.Scope != "" && dep.Scope != ScopeCompile {
+		fields = append(fields, dependencyField{"scope", strconv.Quote(dep.Scope)})
+	}
+	return fields
+}
+
+// formatDependency renders a dependency as an inline table.
+func formatDependency(dep Dependency) string {
+	var fields []string
+	for _, field := range dependencyFields(dep) {
+		fields = append(fields, field.key+" = "+field.value)
+	}
+	return "{ " + strings.Join(fields, ", ") + " }"
+}
+
+// SaveDependency adds or updates a dependency in amber.toml. Only the
+// dependency's own entry is rewritten, everything else is kept as written.
+func SaveDependency(name string, dependency Dependency) error {
+	doc, err := ReadDocument("amber.toml")
 	if err != nil {
 		return err
 	}
 
-	// Replace the dependencies section with the new one
-	re := regexp.MustCompile(`(?s)\[dependencies\].*?(\n\[|$)`)
-	newContent := re.ReplaceAllString(string(content), dependencies+"$1")
+	// A dependency written as a [dependencies."name"] table keeps that form
+	table := []string{"dependencies", name}
+	if !doc.HasTable(table...) {
+		if err := doc.Set([]string{"dependencies"}, name, formatDependency(dependency)); err != nil {
+			return err
+		}
+		return doc.Save()
+	}
 
-	return os.WriteFile(filename, []byte(newContent), 0644)
+	fields := map[string]string{}
+	for _, field := range dependencyFields(dependency) {
+		fields[field.key] = field.value
+	}
+	for _, key := range dependencyKeys {
+		var err error
+		if value, ok := fields[key]; ok {
+			err = doc.Set(table, key, value)
+		} else {
+			_, err = doc.Delete(table, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return doc.Save()
 }
 
-func generateDependenciesSection(dependencies map[string]Dependency) string {
-	var sb strings.Builder
-	sb.WriteString("[dependencies]\n")
-	for name, dep := range dependencies {
-		sb.WriteString(fmt.Sprintf(`"%s" = { origin = "%s", version = "%s" }`+"\n", name, dep.Origin, dep.Version))
+// RemoveDependency deletes a dependency from amber.toml. Only the lines
+// declaring it are dropped, everything else is kept as written.
+func RemoveDependency(name string) error {
+	doc, err := ReadDocument("amber.toml")
+	if err != nil {
+		return err
 	}
-	return sb.String()
-}
 
-func SaveDependency(name, origin, version string) error {
-	config, err := GetTomlConfig()
+	found, err := doc.Delete([]string{"dependencies"}, name)
 	if err != nil {
 		return err
 	}
+	if removed, err := doc.DeleteTable("dependencies", name); err != nil {
+		return err
+	} else if removed {
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("%s is not a dependency of this project", name)
+	}
+	return doc.Save()
+}
 
-	if config.Dependencies == nil {
-		config.Dependencies = make(map[string]Dependency)
+// AddDependency declares a dependency after the ones cfg already has.
+func (c *Config) AddDependency(name string, dependency Dependency) {
+	if c.Dependencies == nil {
+		c.Dependencies = map[string]Dependency{}
+	}
+	if _, ok := c.Dependencies[name]; !ok {
+		c.dependencyOrder = append(c.dependencyOrder, name)
 	}
+	c.Dependencies[name] = dependency
+}
 
-	config.Dependencies[name] = Dependency{
-		Origin:  origin,
-		Version: version,
+// AddRepository declares a repository after the ones cfg already has.
+func (c *Config) AddRepository(id, url string) {
+	if c.Repositories == nil {
+		c.Repositories = map[string]string{}
+	}
+	if _, ok := c.Repositories[id]; !ok {
+		c.repositoryOrder = append(c.repositoryOrder, id)
 	}
+	c.Repositories[id] = url
+}
 
-	// Generate the new dependencies section
-	dependenciesSection := generateDependenciesSection(config.Dependencies)
+// CreateConfig writes cfg as a new amber.toml at path, with its
+// repositories and dependencies in declaration order.
+func CreateConfig(path string, cfg *Config) error {
+	doc := &Document{path: path}
+	root := []struct{ key, value string }{
+		{"main_class", cfg.MainClass},
+		{"release", cfg.Release},
+		{"src_dir", cfg.SrcDir},
+		{"group", cfg.Group},
+		{"name", cfg.Name},
+		{"version", cfg.Version},
+		{"description", cfg.Description},
+		{"url", cfg.URL},
+		{"license", cfg.License},
+	}
+	for _, field := range root {
+		if field.value == "" && field.key != "main_class" {
+			continue
+		}
+		if err := doc.Set(nil, field.key, strconv.Quote(field.value)); err != nil {
+			return err
+		}
+	}
 
-	return saveConfig("amber.toml", dependenciesSection)
+	for _, repository := range cfg.MavenRepositories() {
+		if repository.ID == CentralID && repository.URL == CentralURL {
+			continue
+		}
+		if err := doc.Set([]string{"repositories"}, repository.ID, strconv.Quote(repository.URL)); err != nil {
+			return err
+		}
+	}
+
+	coordinates := make([]string, 0, len(cfg.Overrides))
+	for coordinate := range cfg.Overrides {
+		coordinates = append(coordinates, coordinate)
+	}
+	sort.Strings(coordinates)
+	for _, coordinate := range coordinates {
+		if err := doc.Set([]string{"overrides"}, coordinate, strconv.Quote(cfg.Overrides[coordinate])); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range cfg.DependencyNames() {
+		if err := doc.Set([]string{"dependencies"}, name, formatDependency(cfg.Dependencies[name])); err != nil {
+			return err
+		}
+	}
+	return doc.Save()
 }
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+// inDir runs the test from dir, where amber.toml is read and written.
+func inDir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestRemoveDependency(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		remove  string
+		want    string
+	}{
+		{
+			name: "inline",
+			content: `[dependencies]
+# logging
+"org.slf4j/slf4j-api" = { origin = "maven", version = "2.0.9" }
+"com.google.guava/guava" = { origin = "maven", version = "32.1.2-jre" }
+`,
+			remove: "org.slf4j/slf4j-api",
+			want: `[dependencies]
+# logging
+"com.google.guava/guava" = { origin = "maven", version = "32.1.2-jre" }
+`,
+		},
+		{
+			name: "multi-line array",
+			content: `[dependencies]
+"a/a" = { origin = "maven", version = "1", exclude = [
+    "x:y",
+    "z:*", # not needed
+] }
+"b/b" = { origin = "maven", version = "2" }
+`,
+			remove: "a/a",
+			want: `[dependencies]
+"b/b" = { origin = "maven", version = "2" }
+`,
+		},
+		{
+			name: "multi-line value of another dependency",
+			content: `[dependencies]
+"b/b" = { origin = "maven", version = "2", exclude = [
+    "a/a = x",
+] }
+"a/a" = { origin = "maven", version = "1" }
+`,
+			remove: "a/a",
+			want: `[dependencies]
+"b/b" = { origin = "maven", version = "2", exclude = [
+    "a/a = x",
+] }
+`,
+		},
+		{
+			name: "sub-table",
+			content: `[dependencies]
+"b/b" = { origin = "maven", version = "2" }
+
+[dependencies."a/a"]
+origin = "maven"
+version = "1"
+
+[overrides]
+`,
+			remove: "a/a",
+			want: `[dependencies]
+"b/b" = { origin = "maven", version = "2" }
+
+[overrides]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inDir(t, t.TempDir())
+			if err := os.WriteFile("amber.toml", []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := RemoveDependency(tt.remove); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile("amber.toml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveDependencyMissing(t *testing.T) {
+	inDir(t, t.TempDir())
+	if err := os.WriteFile("amber.toml", []byte("[dependencies]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveDependency("a/a"); err == nil {
+		t.Error("removing an undeclared dependency succeeded")
+	}
+}
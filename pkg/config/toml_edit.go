@@ -0,0 +1,457 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Document is a TOML file as written. Keys can be set and deleted one at a
+// time, every other line, comment and blank line is kept byte for byte.
+type Document struct {
+	path  string
+	lines []string
+}
+
+// tomlEntry is a key/value pair, which may span several lines when its
+// value is a multi-line array or string.
+type tomlEntry struct {
+	key        []string
+	start, end int
+	// keyText is everything before the =, trailer everything after the
+	// value on its last line (a comment and the line ending).
+	keyText string
+	trailer string
+}
+
+// tomlTable is a [header] and the entries up to the next header. The root
+// table has no header.
+type tomlTable struct {
+	path    []string
+	header  int
+	entries []tomlEntry
+}
+
+// ReadDocument loads the TOML file at path for editing.
+func ReadDocument(path string) (*Document, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{path: path, lines: strings.SplitAfter(string(content), "\n")}, nil
+}
+
+// parse splits the document into tables and their entries.
+func (d *Document) parse() ([]*tomlTable, error) {
+	current := &tomlTable{header: -1}
+	tables := []*tomlTable{current}
+	for i := 0; i < len(d.lines); i++ {
+		line := d.lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "[") {
+			name := strings.TrimPrefix(strings.TrimPrefix(trimmed, "["), "[")
+			end := closingBracket(name)
+			if end < 0 {
+				return nil, fmt.Errorf("%s:%d: unterminated table header", d.path, i+1)
+			}
+			path, err := splitKey(name[:end])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", d.path, i+1, err)
+			}
+			current = &tomlTable{path: path, header: i}
+			tables = append(tables, current)
+			continue
+		}
+
+		eq := keyEnd(line)
+		if eq < 0 {
+			return nil, fmt.Errorf("%s:%d: expected key = value", d.path, i+1)
+		}
+		key, err := splitKey(line[:eq])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", d.path, i+1, err)
+		}
+		endLine, endPos := valueEnd(d.lines, i, eq+1)
+		current.entries = append(current.entries, tomlEntry{
+			key:     key,
+			start:   i,
+			end:     endLine,
+			keyText: line[:eq],
+			trailer: d.lines[endLine][endPos:],
+		})
+		i = endLine
+	}
+	return tables, nil
+}
+
+// findTable returns the table with the given path, nil when there is none.
+func findTable(tables []*tomlTable, path []string) *tomlTable {
+	for _, table := range tables {
+		if samePath(table.path, path) {
+			return table
+		}
+	}
+	return nil
+}
+
+// samePath compares key paths. Table names are compared ignoring case, as
+// amber.toml is decoded case insensitively.
+func samePath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if i == 0 && !strings.EqualFold(a[i], b[i]) || i > 0 && a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// HasTable reports whether the document has a [header] for path.
+func (d *Document) HasTable(path ...string) bool {
+	tables, err := d.parse()
+	return err == nil && findTable(tables, path) != nil
+}
+
+// Set gives key in the table at path the rendered TOML value. An existing
+// entry is updated in place, keeping its key as written and its comment,
+// and a new one is added after the last entry of the table. The table is
+// created at the end of the document when it does not exist.
+func (d *Document) Set(path []string, key, value string) error {
+	tables, err := d.parse()
+	if err != nil {
+		return err
+	}
+
+	table := findTable(tables, path)
+	if table == nil {
+		d.appendTable(path, formatKey(key)+" = "+value+"\n")
+		return nil
+	}
+
+	// Dotted keys such as name.version = "1.0" are folded into one entry
+	var matches []tomlEntry
+	for _, entry := range table.entries {
+		if entry.key[0] == key {
+			matches = append(matches, entry)
+		}
+	}
+	if len(matches) == 1 && len(matches[0].key) == 1 {
+		entry := matches[0]
+		d.replace(entry.start, entry.end+1, strings.TrimRight(entry.keyText, " \t")+" = "+value+entry.trailer)
+		return nil
+	}
+	if len(matches) > 0 {
+		first := matches[0]
+		indent := first.keyText[:len(first.keyText)-len(strings.TrimLeft(first.keyText, " \t"))]
+		for i := len(matches) - 1; i > 0; i-- {
+			d.replace(matches[i].start, matches[i].end+1)
+		}
+		d.replace(first.start, first.end+1, indent+formatKey(key)+" = "+value+lineEnding(d.lines[first.end]))
+		return nil
+	}
+
+	at := table.header + 1
+	indent := ""
+	if n := len(table.entries); n > 0 {
+		last := table.entries[n-1]
+		at = last.end + 1
+		indent = last.keyText[:len(last.keyText)-len(strings.TrimLeft(last.keyText, " \t"))]
+	}
+	if at > 0 && !strings.HasSuffix(d.lines[at-1], "\n") {
+		d.lines[at-1] += "\n"
+	}
+	d.replace(at, at, indent+formatKey(key)+" = "+value+"\n")
+	return nil
+}
+
+// Delete removes key from the table at path and reports whether it was
+// there.
+func (d *Document) Delete(path []string, key string) (bool, error) {
+	tables, err := d.parse()
+	if err != nil {
+		return false, err
+	}
+	table := findTable(tables, path)
+	if table == nil {
+		return false, nil
+	}
+
+	found := false
+	for i := len(table.entries) - 1; i >= 0; i-- {
+		if entry := table.entries[i]; entry.key[0] == key {
+			d.replace(entry.start, entry.end+1)
+			found = true
+		}
+	}
+	return found, nil
+}
+
+// DeleteTable removes the [header] of path together with its entries and
+// reports whether it was there. Comments and blank lines after the last
+// entry are left to whatever follows.
+func (d *Document) DeleteTable(path ...string) (bool, error) {
+	tables, err := d.parse()
+	if err != nil {
+		return false, err
+	}
+	table := findTable(tables, path)
+	if table == nil || table.header < 0 {
+		return false, nil
+	}
+
+	end := table.header + 1
+	if n := len(table.entries); n > 0 {
+		end = table.entries[n-1].end + 1
+	}
+	// Keep a single blank line between the tables around it
+	if end < len(d.lines) && strings.TrimSpace(d.lines[end]) == "" && (table.header == 0 || strings.TrimSpace(d.lines[table.header-1]) == "") {
+		end++
+	}
+	d.replace(table.header, end)
+	return true, nil
+}
+
+func (d *Document) appendTable(path []string, body string) {
+	content := strings.Join(d.lines, "")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if content != "" && !strings.HasSuffix(content, "\n\n") {
+		content += "\n"
+	}
+	var names []string
+	for _, name := range path {
+		names = append(names, formatKey(name))
+	}
+	content += "[" + strings.Join(names, ".") + "]\n" + body
+	d.lines = strings.SplitAfter(content, "\n")
+}
+
+// replace swaps lines [start, end) for the given ones.
+func (d *Document) replace(start, end int, lines ...string) {
+	d.lines = append(d.lines[:start], append(lines, d.lines[end:]...)...)
+}
+
+func (d *Document) String() string {
+	return strings.Join(d.lines, "")
+}
+
+// Save writes the document back, replacing the file only once the new
+// content is fully written.
+func (d *Document) Save() error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(d.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), filepath.Base(d.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(d.String()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), d.path)
+}
+
+func lineEnding(line string) string {
+	if strings.HasSuffix(line, "\r\n") {
+		return "\r\n"
+	}
+	if strings.HasSuffix(line, "\n") {
+		return "\n"
+	}
+	return ""
+}
+
+// formatKey quotes a key unless it is a bare key.
+func formatKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return strconv.Quote(key)
+		}
+	}
+	return key
+}
+
+// splitKey parses a possibly dotted and quoted key into its parts.
+func splitKey(text string) ([]string, error) {
+	var parts []string
+	rest := strings.TrimSpace(text)
+	for {
+		var part string
+		switch {
+		case strings.HasPrefix(rest, `"`):
+			end := stringEnd(rest, 1, '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated key %s", text)
+			}
+			unquoted, err := strconv.Unquote(rest[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %s", text)
+			}
+			part, rest = unquoted, rest[end+1:]
+		case strings.HasPrefix(rest, "'"):
+			end := strings.IndexByte(rest[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated key %s", text)
+			}
+			part, rest = rest[1:end+1], rest[end+2:]
+		default:
+			end := strings.IndexAny(rest, ". \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			part, rest = rest[:end], rest[end:]
+			if part == "" {
+				return nil, fmt.Errorf("invalid key %q", text)
+			}
+		}
+		parts = append(parts, part)
+
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			return parts, nil
+		}
+		if !strings.HasPrefix(rest, ".") {
+			return nil, fmt.Errorf("invalid key %q", text)
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+}
+
+// stringEnd returns the index of the quote closing the basic or literal
+// string that starts before pos, -1 when it is not closed on this line.
+func stringEnd(line string, pos int, quote byte) int {
+	for ; pos < len(line); pos++ {
+		switch line[pos] {
+		case '\\':
+			if quote == '"' {
+				pos++
+			}
+		case quote:
+			return pos
+		}
+	}
+	return -1
+}
+
+// closingBracket returns the index of the ] ending a table header.
+func closingBracket(header string) int {
+	for i := 0; i < len(header); i++ {
+		switch header[i] {
+		case '"', '\'':
+			end := stringEnd(header, i+1, header[i])
+			if end < 0 {
+				return -1
+			}
+			i = end
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// keyEnd returns the index of the = separating the key from the value.
+func keyEnd(line string) int {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"', '\'':
+			end := stringEnd(line, i+1, line[i])
+			if end < 0 {
+				return -1
+			}
+			i = end
+		case '=':
+			return i
+		case '#', '\n':
+			return -1
+		}
+	}
+	return -1
+}
+
+// valueEnd finds the end of the value starting at lines[i][pos], which
+// continues over later lines while arrays, inline tables or multi-line
+// strings are open. It returns the last line of the value and the index
+// just after the value on that line.
+func valueEnd(lines []string, i, pos int) (int, int) {
+	depth := 0
+	multiline := ""
+	for ; i < len(lines); i, pos = i+1, 0 {
+		line := lines[i]
+		end := -1
+		for pos < len(line) && end < 0 {
+			if multiline != "" {
+				close := strings.Index(line[pos:], multiline)
+				if close < 0 {
+					pos = len(line)
+					continue
+				}
+				// A closing """ may be preceded by up to two more quotes
+				pos += close + 3
+				for pos < len(line) && line[pos] == multiline[0] {
+					pos++
+				}
+				multiline = ""
+				continue
+			}
+
+			switch c := line[pos]; {
+			case strings.HasPrefix(line[pos:], `"""`) || strings.HasPrefix(line[pos:], `'''`):
+				multiline = line[pos : pos+3]
+				pos += 3
+			case c == '"' || c == '\'':
+				close := stringEnd(line, pos+1, c)
+				if close < 0 {
+					close = len(line) - 1
+				}
+				pos = close + 1
+			case c == '[' || c == '{':
+				depth++
+				pos++
+			case c == ']' || c == '}':
+				depth--
+				pos++
+			case c == '#' || c == '\r' || c == '\n':
+				end = pos
+			default:
+				pos++
+			}
+		}
+		if multiline != "" || depth > 0 {
+			continue
+		}
+		if end < 0 {
+			end = len(line)
+		}
+		// Leave the spaces before a comment with the comment
+		for end > 0 && (line[end-1] == ' ' || line[end-1] == '\t') {
+			end--
+		}
+		return i, end
+	}
+	last := len(lines) - 1
+	return last, len(lines[last])
+}
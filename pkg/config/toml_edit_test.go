@@ -0,0 +1,231 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+const testDocument = `# amber.toml
+main_class = "Main" # entry point
+
+[repositories]
+local = "file:///tmp/repo"
+
+# pinned for the whole graph
+[overrides]
+"com.ex:b" = "2.0"
+
+[dependencies]
+# logging
+"org.slf4j/slf4j-api" = { origin = "maven", version = "2.0.9" } # keep
+"com.ex/a" = { origin = "maven", version = "1.0", exclude = [
+    "com.ex:c", # not needed
+    "com.ex:[d]",
+] }
+notes = """
+a = "not a key"
+[not.a.table]
+"""
+literal = '''
+]]] '''
+"com.ex/z" = { origin = "maven", version = "3.0" }
+`
+
+func newTestDocument(content string) *Document {
+	return &Document{path: "amber.toml", lines: strings.SplitAfter(content, "\n")}
+}
+
+// checkDocument compares the edited document and makes sure it still
+// decodes.
+func checkDocument(t *testing.T, d *Document, want string) {
+	t.Helper()
+	got := d.String()
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	var decoded map[string]any
+	if _, err := toml.Decode(got, &decoded); err != nil {
+		t.Errorf("edited document does not parse: %v\n%s", err, got)
+	}
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+	d := newTestDocument(testDocument)
+	if _, err := d.parse(); err != nil {
+		t.Fatal(err)
+	}
+	checkDocument(t, d, testDocument)
+}
+
+func TestDocumentSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  []string
+		key   string
+		value string
+		want  string
+	}{
+		{
+			name:  "update keeps the comment",
+			path:  nil,
+			key:   "main_class",
+			value: `"app.Main"`,
+			want:  strings.Replace(testDocument, `main_class = "Main" # entry point`, `main_class = "app.Main" # entry point`, 1),
+		},
+		{
+			name:  "update a quoted key",
+			path:  []string{"dependencies"},
+			key:   "org.slf4j/slf4j-api",
+			value: `{ origin = "maven", version = "2.0.13" }`,
+			want:  strings.Replace(testDocument, `version = "2.0.9" } # keep`, `version = "2.0.13" } # keep`, 1),
+		},
+		{
+			name:  "update a multi-line value",
+			path:  []string{"dependencies"},
+			key:   "com.ex/a",
+			value: `{ origin = "maven", version = "1.1" }`,
+			want: strings.Replace(testDocument, `"com.ex/a" = { origin = "maven", version = "1.0", exclude = [
+    "com.ex:c", # not needed
+    "com.ex:[d]",
+] }`, `"com.ex/a" = { origin = "maven", version = "1.1" }`, 1),
+		},
+		{
+			name:  "add after the last entry",
+			path:  []string{"repositories"},
+			key:   "company",
+			value: `"https://repo.example.com"`,
+			want:  strings.Replace(testDocument, "local = \"file:///tmp/repo\"\n", "local = \"file:///tmp/repo\"\ncompany = \"https://repo.example.com\"\n", 1),
+		},
+		{
+			name:  "add a table",
+			path:  []string{"publish"},
+			key:   "repository",
+			value: `"local"`,
+			want:  testDocument + "\n[publish]\nrepository = \"local\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDocument(testDocument)
+			if err := d.Set(tt.path, tt.key, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			checkDocument(t, d, tt.want)
+		})
+	}
+}
+
+func TestDocumentSetDottedKeys(t *testing.T) {
+	d := newTestDocument("[dependencies]\na.origin = \"maven\" # first\nb = 1\na.version = \"1.0\"\n")
+	if err := d.Set([]string{"dependencies"}, "a", `{ origin = "maven", version = "2.0" }`); err != nil {
+		t.Fatal(err)
+	}
+	checkDocument(t, d, "[dependencies]\na = { origin = \"maven\", version = \"2.0\" }\nb = 1\n")
+}
+
+func TestDocumentDelete(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{
+			name: "inline table",
+			key:  "org.slf4j/slf4j-api",
+			want: strings.Replace(testDocument, "\"org.slf4j/slf4j-api\" = { origin = \"maven\", version = \"2.0.9\" } # keep\n", "", 1),
+		},
+		{
+			name: "multi-line array",
+			key:  "com.ex/a",
+			want: strings.Replace(testDocument, `"com.ex/a" = { origin = "maven", version = "1.0", exclude = [
+    "com.ex:c", # not needed
+    "com.ex:[d]",
+] }
+`, "", 1),
+		},
+		{
+			name: "multi-line string",
+			key:  "notes",
+			want: strings.Replace(testDocument, `notes = """
+a = "not a key"
+[not.a.table]
+"""
+`, "", 1),
+		},
+		{
+			name: "multi-line literal string",
+			key:  "literal",
+			want: strings.Replace(testDocument, "literal = '''\n]]] '''\n", "", 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDocument(testDocument)
+			found, err := d.Delete([]string{"dependencies"}, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !found {
+				t.Fatalf("%s was not found", tt.key)
+			}
+			checkDocument(t, d, tt.want)
+		})
+	}
+
+	d := newTestDocument(testDocument)
+	if found, err := d.Delete([]string{"dependencies"}, "missing"); err != nil || found {
+		t.Errorf("Delete(missing) = %v, %v", found, err)
+	}
+	checkDocument(t, d, testDocument)
+}
+
+func TestDocumentDeleteTable(t *testing.T) {
+	d := newTestDocument(testDocument)
+	found, err := d.DeleteTable("overrides")
+	if err != nil || !found {
+		t.Fatalf("DeleteTable(overrides) = %v, %v", found, err)
+	}
+	// The comment above the header belongs to whatever precedes it, so
+	// the blank line after the table stays to separate it from the next
+	checkDocument(t, d, strings.Replace(testDocument, "[overrides]\n\"com.ex:b\" = \"2.0\"\n", "", 1))
+
+	d = newTestDocument(testDocument)
+	found, err = d.DeleteTable("repositories")
+	if err != nil || !found {
+		t.Fatalf("DeleteTable(repositories) = %v, %v", found, err)
+	}
+	checkDocument(t, d, strings.Replace(testDocument, "[repositories]\nlocal = \"file:///tmp/repo\"\n\n", "", 1))
+
+	if found, err := d.DeleteTable("publish"); err != nil || found {
+		t.Errorf("DeleteTable(publish) = %v, %v", found, err)
+	}
+}
+
+func TestValueEnd(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		pos     int
+	}{
+		{"string", `a = "x # y" # comment`, 0, 11},
+		{"escaped quote", `a = "x \" ]" `, 0, 12},
+		{"literal", `a = 'C:\path' # dir`, 0, 13},
+		{"inline table", `a = { b = [1, 2], c = "}" }`, 0, 27},
+		{"array", "a = [\n  1, # one\n  [2, 3],\n]\nb = 1", 3, 1},
+		{"basic multi-line", "a = \"\"\"\nx = ]\n\"\"\"\"\" # quotes\nb = 1", 2, 5},
+		{"literal multi-line", "a = '''\n[x]\n''' \nb = 1", 2, 3},
+		{"unterminated", "a = [\n1,", 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := strings.SplitAfter(tt.content, "\n")
+			line, pos := valueEnd(lines, 0, strings.Index(lines[0], "=")+1)
+			if line != tt.line || pos != tt.pos {
+				t.Errorf("valueEnd = %d, %d, want %d, %d", line, pos, tt.line, tt.pos)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"jpkg/pkg/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	FileName = "dependencies-lock.json"
+	Version  = 2
+)
+
+// Artifact is a single installed jar together with everything needed to
+// fetch and verify exactly the same bytes again.
+type Artifact struct {
+	GroupID    string `json:"groupId"`
+	ArtifactID string `json:"artifactId"`
+	Version    string `json:"version"`
+	Classifier string `json:"classifier,omitempty"`
+	Type       string `json:"type,omitempty"`
+	Origin     string `json:"origin"`
+	Scope      string `json:"scope"`
+	File       string `json:"file"`
+	Repository string `json:"repository,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+	// Built is set for jars jpkg compiled from source. URL and Hash then
+	// describe the source archive, as builds are not byte for byte
+	// reproducible. Path dependencies record their directory as URL.
+	Built bool `json:"built,omitempty"`
+	// Dependencies lists the groupId:artifactId:version this artifact asks
+	// for. The version is the requested one, which may have lost a conflict.
+	Dependencies []string `json:"dependencies,omitempty"`
+	// Excluded lists the groupId:artifactId:version this artifact asks for
+	// that an exclusion kept out of the graph.
+	Excluded []string `json:"excluded,omitempty"`
+}
+
+type Lock struct {
+	LockfileVersion int    `json:"lockfileVersion"`
+	Strategy        string `json:"strategy,omitempty"`
+	// Overrides is a copy of [overrides] from amber.toml, the versions
+	// forced on the whole graph.
+	Overrides map[string]string `json:"overrides,omitempty"`
+	// Declared is a copy of [dependencies] from amber.toml at the time the
+	// lock was written, used to detect drift.
+	Declared  map[string]config.Dependency `json:"declared"`
+	Artifacts map[string]*Artifact         `json:"artifacts"`
+}
+
+// Key identifies the artifact in the lock: groupId:artifactId, followed
+// by the classifier if any, for maven artifacts, path:name for path
+// dependencies and the amber.toml name (user/repo) for everything else.
+func (a *Artifact) Key() string {
+	if a.Origin == "maven" && a.Classifier != "" {
+		return a.GroupID + ":" + a.ArtifactID + ":" + a.Classifier
+	}
+	if a.Origin == "maven" || a.Origin == "path" {
+		return a.GroupID + ":" + a.ArtifactID
+	}
+	return a.GroupID + "/" + a.ArtifactID
+}
+
+func (a *Artifact) String() string {
+	if a.Version == "" {
+		return a.Key()
+	}
+	return a.Key() + ":" + a.Version
+}
+
+func New() *Lock {
+	return &Lock{
+		LockfileVersion: Version,
+		Declared:        map[string]config.Dependency{},
+		Artifacts:       map[string]*Artifact{},
+	}
+}
+
+// Read loads the lock file of the current project. A missing lock file, or
+// one written by an older jpkg, yields an empty lock.
+func Read() (*Lock, error) {
+	content, err := os.ReadFile(FileName)
+	if os.IsNotExist(err) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var header struct {
+		LockfileVersion int `json:"lockfileVersion"`
+	}
+	if err := json.Unmarshal(content, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FileName, err)
+	}
+	if header.LockfileVersion < Version {
+		return New(), nil
+	}
+	if header.LockfileVersion > Version {
+		return nil, fmt.Errorf("%s was written by a newer jpkg (lockfile version %d)", FileName, header.LockfileVersion)
+	}
+
+	lock := New()
+	if err := json.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FileName, err)
+	}
+	if lock.Declared == nil {
+		lock.Declared = map[string]config.Dependency{}
+	}
+	if lock.Artifacts == nil {
+		lock.Artifacts = map[string]*Artifact{}
+	}
+	return lock, nil
+}
+
+// Write saves the lock file, replacing the old one only once the new
+// content is fully written.
+func (l *Lock) Write() error {
+	l.LockfileVersion = Version
+	for _, a := range l.Artifacts {
+		sort.Strings(a.Dependencies)
+		sort.Strings(a.Excluded)
+	}
+
+	content, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(FileName), FileName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(content, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), FileName)
+}
+
+// Keys returns the artifact keys in sorted order.
+func (l *Lock) Keys() []string {
+	keys := make([]string, 0, len(l.Artifacts))
+	for key := range l.Artifacts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Find returns the artifacts matching name, which is either a lock key,
+// groupId/artifactId or a bare artifactId.
+func (l *Lock) Find(name string) []*Artifact {
+	if a, ok := l.Artifacts[name]; ok {
+		return []*Artifact{a}
+	}
+	if a, ok := l.Artifacts[strings.Replace(name, "/", ":", 1)]; ok {
+		return []*Artifact{a}
+	}
+
+	var found []*Artifact
+	for _, key := range l.Keys() {
+		if l.Artifacts[key].ArtifactID == name {
+			found = append(found, l.Artifacts[key])
+		}
+	}
+	return found
+}
+
+// Drift lists the differences between the dependencies declared in
+// amber.toml and the ones the lock was resolved from.
+func (l *Lock) Drift(cfg *config.Config) []string {
+	var drift []string
+
+	strategy, err := cfg.ConflictStrategy()
+	if err != nil {
+		drift = append(drift, err.Error())
+	} else if l.Strategy != "" && l.Strategy != strategy {
+		drift = append(drift, fmt.Sprintf("resolution strategy changed from %s to %s", l.Strategy, strategy))
+	}
+
+	for _, name := range cfg.DependencyNames() {
+		declared := cfg.Dependencies[name]
+		locked, ok := l.Declared[name]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("%s was added", name))
+			continue
+		}
+		if declaredJSON, lockedJSON := declaration(declared), declaration(locked); declaredJSON != lockedJSON {
+			drift = append(drift, fmt.Sprintf("%s changed from %s to %s", name, lockedJSON, declaredJSON))
+		}
+	}
+
+	var overrides []string
+	for coordinate, version := range cfg.Overrides {
+		if locked, ok := l.Overrides[coordinate]; !ok {
+			overrides = append(overrides, fmt.Sprintf("override of %s was added", coordinate))
+		} else if locked != version {
+			overrides = append(overrides, fmt.Sprintf("override of %s changed from %s to %s", coordinate, locked, version))
+		}
+	}
+	for coordinate := range l.Overrides {
+		if _, ok := cfg.Overrides[coordinate]; !ok {
+			overrides = append(overrides, fmt.Sprintf("override of %s was removed", coordinate))
+		}
+	}
+	sort.Strings(overrides)
+	drift = append(drift, overrides...)
+
+	var removed []string
+	for name := range l.Declared {
+		if _, ok := cfg.Dependencies[name]; !ok {
+			removed = append(removed, fmt.Sprintf("%s was removed", name))
+		}
+	}
+	sort.Strings(removed)
+	return append(drift, removed...)
+}
+
+// declaration returns dep in the form declarations are compared in.
+func declaration(dep config.Dependency) string {
+	dep.Scope = dep.EffectiveScope()
+	content, _ := json.Marshal(dep)
+	return string(content)
+}
+
+// Changed reports whether dep, declared as name in amber.toml, differs
+// from the declaration the lock was resolved from.
+func (l *Lock) Changed(name string, dep config.Dependency) bool {
+	locked, ok := l.Declared[name]
+	return !ok || declaration(locked) != declaration(dep)
+}
+
+// RootKey returns the lock key of a dependency declared in amber.toml.
+func RootKey(name string, dep config.Dependency) string {
+	switch dep.Origin {
+	case "maven":
+		name, _, _ = strings.Cut(name, ":")
+		key := strings.Replace(name, "/", ":", 1)
+		if classifier := config.TypeClassifier(dep.Type, dep.Classifier); classifier != "" {
+			key += ":" + classifier
+		}
+		return key
+	case "path":
+		return "path:" + name
+	default:
+		return name
+	}
+}
+
+// edgeKey turns a groupId:artifactId[:classifier]:version edge into an
+// artifact key.
+func edgeKey(edge string) string {
+	if strings.Count(edge, ":") < 2 {
+		return edge
+	}
+	return edge[:strings.LastIndex(edge, ":")]
+}
+
+// reachable returns the keys of the artifacts that can be reached from the
+// given keys, including those.
+func (l *Lock) reachable(queue []string) map[string]bool {
+	reachable := map[string]bool{}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		a, ok := l.Artifacts[key]
+		if !ok || reachable[key] {
+			continue
+		}
+		reachable[key] = true
+		for _, edge := range a.Dependencies {
+			queue = append(queue, edgeKey(edge))
+		}
+	}
+	return reachable
+}
+
+// Transitive returns every artifact the artifact with the given key
+// depends on, directly or not.
+func (l *Lock) Transitive(key string) []*Artifact {
+	var found []*Artifact
+	reachable := l.reachable([]string{key})
+	for _, k := range l.Keys() {
+		if reachable[k] && k != key {
+			found = append(found, l.Artifacts[k])
+		}
+	}
+	return found
+}
+
+// Prune drops every artifact that can no longer be reached from the
+// declared dependencies and returns the dropped artifacts.
+func (l *Lock) Prune() []*Artifact {
+	var roots []string
+	for name, dep := range l.Declared {
+		roots = append(roots, RootKey(name, dep))
+	}
+	reachable := l.reachable(roots)
+
+	var pruned []*Artifact
+	for _, key := range l.Keys() {
+		if !reachable[key] {
+			pruned = append(pruned, l.Artifacts[key])
+			delete(l.Artifacts, key)
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"jpkg/pkg/config"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Entry describes a file held by the store. Files are stored once per
+// content (keyed by SHA256) and found through an index of keys such as the
+// Maven repository path of an artifact.
+type Entry struct {
+	SHA256 string `json:"sha256"`
+	// Hash is the verified hash recorded in lock files, which may use a
+	// different algorithm than the one the store is keyed by.
+	Hash       string `json:"hash,omitempty"`
+	Repository string `json:"repository,omitempty"`
+	URL        string `json:"url,omitempty"`
+}
+
+// Dir returns the root of the store, shared by every project of the user.
+func Dir() (string, error) {
+	home, err := config.AmberHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "store"), nil
+}
+
+func blobPath(root, sum string) string {
+	return filepath.Join(root, "blobs", "sha256", sum[:2], sum)
+}
+
+func indexPath(root, key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid store key %q", key)
+	}
+	return filepath.Join(root, "index", clean+".json"), nil
+}
+
+// Lookup returns the entry stored under key, if its content is present.
+func Lookup(key string) (*Entry, bool) {
+	root, err := Dir()
+	if err != nil {
+		return nil, false
+	}
+	path, err := indexPath(root, key)
+	if err != nil {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(content, &entry); err != nil || len(entry.SHA256) < 2 {
+		return nil, false
+	}
+	if _, err := os.Stat(blobPath(root, entry.SHA256)); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Add copies content into the store and indexes it under key.
+func Add(key string, content io.Reader, entry Entry) (*Entry, error) {
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	path, err := indexPath(root, key)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir := filepath.Join(root, "tmp")
+	if err := os.MkdirAll(tmpDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(tmpDir, "blob-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	blob := blobPath(root, entry.SHA256)
+	if _, err := os.Stat(blob); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(blob), os.ModePerm); err != nil {
+			return nil, err
+		}
+		// Blobs are shared through hard links, so keep them read only
+		if err := os.Chmod(tmp.Name(), 0444); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tmp.Name(), blob); err != nil {
+			return nil, err
+		}
+	}
+
+	index, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path+".tmp", index, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// AddFile copies the file at path into the store and indexes it under key.
+func AddFile(key, path string, entry Entry) (*Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Add(key, file, entry)
+}
+
+// Open opens the stored content of the entry.
+func Open(entry *Entry) (*os.File, error) {
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(blobPath(root, entry.SHA256))
+}
+
+// Link places the stored content at dest, as a hard link when the store
+// and dest share a file system and as a copy otherwise.
+func Link(entry *Entry, dest string) error {
+	root, err := Dir()
+	if err != nil {
+		return err
+	}
+	blob := blobPath(root, entry.SHA256)
+
+	if err := os.Link(blob, dest); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(blob)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,314 @@
+package pom
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Fetcher returns the raw pom.xml for the given coordinates.
+type Fetcher func(groupID, artifactID, version string) (io.ReadCloser, error)
+
+// Resolver computes effective models the way Maven does: the parent chain
+// is merged first, then properties are interpolated, imported boms are
+// pulled into dependencyManagement and finally managed versions and scopes
+// are applied to the declared dependencies. Models are cached, so a single
+// Resolver should be reused for a whole dependency graph.
+type Resolver struct {
+	Fetch Fetcher
+
+	inherited map[string]*Project
+	effective map[string]*Project
+	loading   map[string]bool
+}
+
+func coordinates(groupID, artifactID, version string) string {
+	return groupID + ":" + artifactID + ":" + version
+}
+
+// Effective returns the effective model of the given artifact.
+func (r *Resolver) Effective(groupID, artifactID, version string) (*Project, error) {
+	key := coordinates(groupID, artifactID, version)
+	if project, ok := r.effective[key]; ok {
+		return project, nil
+	}
+	if r.effective == nil {
+		r.effective = make(map[string]*Project)
+	}
+	if r.loading == nil {
+		r.loading = make(map[string]bool)
+	}
+	if r.loading[key] {
+		return nil, fmt.Errorf("cycle detected while importing %s", key)
+	}
+	r.loading[key] = true
+	defer delete(r.loading, key)
+
+	inherited, err := r.inherit(groupID, artifactID, version, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	project := inherited.clone()
+
+	project.interpolate()
+	if err := r.importBoms(project); err != nil {
+		return nil, err
+	}
+	project.applyManagement()
+
+	r.effective[key] = project
+	return project, nil
+}
+
+func (r *Resolver) load(groupID, artifactID, version string) (*Project, error) {
+	if r.Fetch == nil {
+		return nil, fmt.Errorf("no pom fetcher configured")
+	}
+	body, err := r.Fetch(groupID, artifactID, version)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	project, err := Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pom for %s: %w", coordinates(groupID, artifactID, version), err)
+	}
+	return project, nil
+}
+
+// inherit returns the raw model of the artifact merged with its parent
+// chain. Nothing is interpolated yet so that properties overridden by a
+// child apply to the values declared in its parents.
+func (r *Resolver) inherit(groupID, artifactID, version string, chain map[string]bool) (*Project, error) {
+	key := coordinates(groupID, artifactID, version)
+	if project, ok := r.inherited[key]; ok {
+		return project, nil
+	}
+	if chain[key] {
+		return nil, fmt.Errorf("cycle detected in parent chain of %s", key)
+	}
+	chain[key] = true
+
+	project, err := r.load(groupID, artifactID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.Parent != nil {
+		parent, err := r.inherit(project.Parent.GroupID, project.Parent.ArtifactID, project.Parent.Version, chain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load parent of %s: %w", key, err)
+		}
+		project.merge(parent)
+	}
+
+	if r.inherited == nil {
+		r.inherited = make(map[string]*Project)
+	}
+	r.inherited[key] = project
+	return project, nil
+}
+
+func (r *Resolver) importBoms(project *Project) error {
+	var managed []Dependency
+	var imports []Dependency
+	for _, dep := range project.DependencyManagement {
+		if dep.Scope == "import" && dep.Type == "pom" {
+			imports = append(imports, dep)
+		} else {
+			managed = append(managed, dep)
+		}
+	}
+
+	// Entries declared directly win over imported ones, and earlier
+	// imports win over later ones.
+	for _, bom := range imports {
+		imported, err := r.Effective(bom.GroupID, bom.ArtifactID, bom.Version)
+		if err != nil {
+			return fmt.Errorf("failed to import bom %s: %w", bom, err)
+		}
+		managed = mergeDependencies(managed, imported.DependencyManagement)
+	}
+
+	project.DependencyManagement = managed
+	return nil
+}
+
+// merge fills in everything the project inherits from its parent.
+func (p *Project) merge(parent *Project) {
+	if p.GroupID == "" {
+		p.GroupID = parent.GroupID
+	}
+	if p.Version == "" {
+		p.Version = parent.Version
+	}
+
+	properties := Properties{}
+	for name, value := range parent.Properties {
+		properties[name] = value
+	}
+	for name, value := range p.Properties {
+		properties[name] = value
+	}
+	p.Properties = properties
+
+	p.DependencyManagement = mergeDependencies(p.DependencyManagement, parent.DependencyManagement)
+	p.Dependencies = mergeDependencies(p.Dependencies, parent.Dependencies)
+}
+
+// mergeDependencies appends the entries of extra that are not already
+// declared in deps.
+func mergeDependencies(deps, extra []Dependency) []Dependency {
+	declared := map[string]bool{}
+	for _, dep := range deps {
+		declared[dep.ManagementKey()] = true
+	}
+	for _, dep := range extra {
+		if !declared[dep.ManagementKey()] {
+			deps = append(deps, dep)
+			declared[dep.ManagementKey()] = true
+		}
+	}
+	return deps
+}
+
+func (p *Project) applyManagement() {
+	managed := map[string]Dependency{}
+	for _, dep := range p.DependencyManagement {
+		managed[dep.ManagementKey()] = dep
+	}
+
+	for i, dep := range p.Dependencies {
+		m, ok := managed[dep.ManagementKey()]
+		if !ok {
+			continue
+		}
+		if dep.Version == "" {
+			dep.Version = m.Version
+		}
+		if dep.Scope == "" {
+			dep.Scope = m.Scope
+		}
+		if len(dep.Exclusions) == 0 {
+			dep.Exclusions = m.Exclusions
+		}
+		p.Dependencies[i] = dep
+	}
+}
+
+func (p *Project) interpolate() {
+	properties := Properties{}
+	for name, value := range p.Properties {
+		properties[name] = value
+	}
+	builtins := map[string]string{
+		"groupId":    p.GroupID,
+		"artifactId": p.ArtifactID,
+		"version":    p.Version,
+		"packaging":  p.Packaging,
+	}
+	for name, value := range builtins {
+		properties["project."+name] = value
+		properties["pom."+name] = value
+		if _, ok := properties[name]; !ok {
+			properties[name] = value
+		}
+	}
+	if p.Parent != nil {
+		properties["project.parent.groupId"] = p.Parent.GroupID
+		properties["project.parent.artifactId"] = p.Parent.ArtifactID
+		properties["project.parent.version"] = p.Parent.Version
+		properties["parent.version"] = p.Parent.Version
+	}
+
+	// The project's own version may come from a property (e.g. ${revision}).
+	p.Version = properties.Expand(p.Version)
+	p.GroupID = properties.Expand(p.GroupID)
+	properties["project.version"] = p.Version
+	properties["pom.version"] = p.Version
+	properties["project.groupId"] = p.GroupID
+	properties["pom.groupId"] = p.GroupID
+
+	for i := range p.DependencyManagement {
+		p.DependencyManagement[i].interpolate(properties)
+	}
+	for i := range p.Dependencies {
+		p.Dependencies[i].interpolate(properties)
+	}
+	p.Properties = properties
+}
+
+func (d *Dependency) interpolate(properties Properties) {
+	d.GroupID = properties.Expand(d.GroupID)
+	d.ArtifactID = properties.Expand(d.ArtifactID)
+	d.Version = properties.Expand(d.Version)
+	d.Type = properties.Expand(d.Type)
+	d.Classifier = properties.Expand(d.Classifier)
+	d.Scope = properties.Expand(d.Scope)
+	d.Optional = properties.Expand(d.Optional)
+	for i := range d.Exclusions {
+		d.Exclusions[i].GroupID = properties.Expand(d.Exclusions[i].GroupID)
+		d.Exclusions[i].ArtifactID = properties.Expand(d.Exclusions[i].ArtifactID)
+	}
+}
+
+// Expand replaces ${name} references with property values. References
+// that cannot be resolved are kept as they are.
+func (p Properties) Expand(value string) string {
+	return p.expandDepth(value, 0)
+}
+
+func (p Properties) expandDepth(value string, depth int) string {
+	if depth > 16 || !strings.Contains(value, "${") {
+		return value
+	}
+
+	var sb strings.Builder
+	rest := value
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		name := rest[start+2 : start+end]
+		sb.WriteString(rest[:start])
+		if replacement, ok := p[name]; ok {
+			sb.WriteString(p.expandDepth(replacement, depth+1))
+		} else {
+			sb.WriteString(rest[start : start+end+1])
+		}
+		rest = rest[start+end+1:]
+	}
+	return sb.String()
+}
+
+func (p *Project) clone() *Project {
+	c := *p
+	if p.Parent != nil {
+		parent := *p.Parent
+		c.Parent = &parent
+	}
+	c.Properties = Properties{}
+	for name, value := range p.Properties {
+		c.Properties[name] = value
+	}
+	c.DependencyManagement = cloneDependencies(p.DependencyManagement)
+	c.Dependencies = cloneDependencies(p.Dependencies)
+	return &c
+}
+
+func cloneDependencies(deps []Dependency) []Dependency {
+	cloned := make([]Dependency, len(deps))
+	for i, dep := range deps {
+		dep.Exclusions = append([]Exclusion(nil), dep.Exclusions...)
+		cloned[i] = dep
+	}
+	return cloned
+}
@@ -0,0 +1,218 @@
+package pom
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// memoryFetch serves poms from a map keyed by group:artifact:version.
+func memoryFetch(poms map[string]string) Fetcher {
+	return func(groupID, artifactID, version string) (io.ReadCloser, error) {
+		content, ok := poms[coordinates(groupID, artifactID, version)]
+		if !ok {
+			return nil, fmt.Errorf("%s not found", coordinates(groupID, artifactID, version))
+		}
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+}
+
+var testPoms = map[string]string{
+	"com.ex:grandparent:1": `<project>
+  <groupId>com.ex</groupId>
+  <artifactId>grandparent</artifactId>
+  <version>1</version>
+  <properties>
+    <lib.version>1.0</lib.version>
+    <log.version>2.0</log.version>
+  </properties>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>com.ex</groupId>
+        <artifactId>lib</artifactId>
+        <version>${lib.version}</version>
+      </dependency>
+      <dependency>
+        <groupId>com.ex</groupId>
+        <artifactId>log</artifactId>
+        <version>${log.version}</version>
+        <scope>runtime</scope>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>
+</project>`,
+	"com.ex:parent:2": `<project>
+  <parent>
+    <groupId>com.ex</groupId>
+    <artifactId>grandparent</artifactId>
+    <version>1</version>
+  </parent>
+  <artifactId>parent</artifactId>
+  <version>2</version>
+  <properties>
+    <lib.version>1.5</lib.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>com.ex</groupId>
+      <artifactId>log</artifactId>
+    </dependency>
+  </dependencies>
+</project>`,
+	"com.ex:app:3.1": `<project>
+  <parent>
+    <groupId>com.ex</groupId>
+    <artifactId>parent</artifactId>
+    <version>2</version>
+  </parent>
+  <artifactId>app</artifactId>
+  <version>3.1</version>
+  <properties>
+    <log.version>2.5</log.version>
+  </properties>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>com.ex</groupId>
+        <artifactId>bom</artifactId>
+        <version>4.0</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+      <dependency>
+        <groupId>com.ex</groupId>
+        <artifactId>pinned</artifactId>
+        <version>1.0</version>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>
+  <dependencies>
+    <dependency>
+      <groupId>com.ex</groupId>
+      <artifactId>lib</artifactId>
+    </dependency>
+    <dependency>
+      <groupId>com.ex</groupId>
+      <artifactId>sibling</artifactId>
+      <version>${project.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>com.ex</groupId>
+      <artifactId>managed-by-bom</artifactId>
+    </dependency>
+    <dependency>
+      <groupId>com.ex</groupId>
+      <artifactId>pinned</artifactId>
+    </dependency>
+  </dependencies>
+</project>`,
+	"com.ex:bom:4.0": `<project>
+  <groupId>com.ex</groupId>
+  <artifactId>bom</artifactId>
+  <version>4.0</version>
+  <packaging>pom</packaging>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>com.ex</groupId>
+        <artifactId>managed-by-bom</artifactId>
+        <version>${project.version}</version>
+      </dependency>
+      <dependency>
+        <groupId>com.ex</groupId>
+        <artifactId>pinned</artifactId>
+        <version>9.9</version>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>
+</project>`,
+}
+
+func TestEffective(t *testing.T) {
+	resolver := &Resolver{Fetch: memoryFetch(testPoms)}
+	project, err := resolver.Effective("com.ex", "app", "3.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if project.GroupID != "com.ex" || project.Version != "3.1" {
+		t.Errorf("coordinates = %s:%s, want com.ex:3.1", project.GroupID, project.Version)
+	}
+
+	got := map[string]Dependency{}
+	for _, dep := range project.Dependencies {
+		got[dep.ArtifactID] = dep
+	}
+	tests := []struct {
+		artifactID string
+		version    string
+		scope      string
+	}{
+		// managed by the grandparent with a property the parent overrides
+		{"lib", "1.5", ""},
+		// declared by the parent, managed by the grandparent with a
+		// property the project overrides
+		{"log", "2.5", "runtime"},
+		{"sibling", "3.1", ""},
+		// ${project.version} of an imported bom is the bom's own version
+		{"managed-by-bom", "4.0", ""},
+		// entries declared directly win over imported ones
+		{"pinned", "1.0", ""},
+	}
+	if len(got) != len(tests) {
+		t.Errorf("got %d dependencies, want %d", len(got), len(tests))
+	}
+	for _, tt := range tests {
+		dep, ok := got[tt.artifactID]
+		if !ok {
+			t.Errorf("%s is missing", tt.artifactID)
+			continue
+		}
+		if dep.Version != tt.version || dep.Scope != tt.scope {
+			t.Errorf("%s = %s (%q), want %s (%q)", tt.artifactID, dep.Version, dep.Scope, tt.version, tt.scope)
+		}
+	}
+}
+
+func TestEffectiveParentCycle(t *testing.T) {
+	poms := map[string]string{
+		"com.ex:a:1": `<project><parent><groupId>com.ex</groupId><artifactId>b</artifactId><version>1</version></parent><artifactId>a</artifactId></project>`,
+		"com.ex:b:1": `<project><parent><groupId>com.ex</groupId><artifactId>a</artifactId><version>1</version></parent><artifactId>b</artifactId></project>`,
+	}
+	resolver := &Resolver{Fetch: memoryFetch(poms)}
+	if _, err := resolver.Effective("com.ex", "a", "1"); err == nil {
+		t.Error("a parent cycle resolved")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	// p0 refers to p1, which refers to p2 and so on up to p19
+	chain := Properties{}
+	for i := 0; i < 20; i++ {
+		chain[fmt.Sprintf("p%d", i)] = fmt.Sprintf("${p%d}", i+1)
+	}
+
+	tests := []struct {
+		name       string
+		properties Properties
+		value      string
+		want       string
+	}{
+		{"plain", Properties{"a": "x"}, "a-${a}-b", "a-x-b"},
+		{"nested", Properties{"a": "${b}.${b}", "b": "y"}, "${a}", "y.y"},
+		{"unresolved", Properties{}, "${missing}-1", "${missing}-1"},
+		{"unterminated", Properties{"a": "x"}, "${a", "${a"},
+		{"self reference", Properties{"a": "${a}"}, "${a}", "${a}"},
+		// expansion stops 16 references deep
+		{"depth cap", chain, "${p0}", "${p17}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.properties.Expand(tt.value); got != tt.want {
+				t.Errorf("Expand(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
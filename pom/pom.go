@@ -0,0 +1,166 @@
+package pom
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Parent struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	// RelativePath locates the parent in a checkout, ../pom.xml when it
+	// is not set.
+	RelativePath *string `xml:"relativePath"`
+}
+
+type Exclusion struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+}
+
+type Dependency struct {
+	GroupID    string      `xml:"groupId"`
+	ArtifactID string      `xml:"artifactId"`
+	Version    string      `xml:"version"`
+	Type       string      `xml:"type"`
+	Classifier string      `xml:"classifier"`
+	Scope      string      `xml:"scope"`
+	Optional   string      `xml:"optional"`
+	Exclusions []Exclusion `xml:"exclusions>exclusion"`
+}
+
+type Properties map[string]string
+
+type Repository struct {
+	ID  string `xml:"id"`
+	URL string `xml:"url"`
+}
+
+// PluginConfiguration holds the plugin settings jpkg understands: main
+// classes of the jar, exec and similar plugins and compiler releases.
+type PluginConfiguration struct {
+	MainClass         string `xml:"mainClass"`
+	ManifestMainClass string `xml:"archive>manifest>mainClass"`
+	Release           string `xml:"release"`
+	Source            string `xml:"source"`
+	Target            string `xml:"target"`
+}
+
+type Plugin struct {
+	GroupID       string              `xml:"groupId"`
+	ArtifactID    string              `xml:"artifactId"`
+	Configuration PluginConfiguration `xml:"configuration"`
+}
+
+type Resource struct {
+	Directory string `xml:"directory"`
+}
+
+type Build struct {
+	SourceDirectory     string     `xml:"sourceDirectory"`
+	TestSourceDirectory string     `xml:"testSourceDirectory"`
+	Resources           []Resource `xml:"resources>resource"`
+	Plugins             []Plugin   `xml:"plugins>plugin"`
+}
+
+type Project struct {
+	GroupID              string       `xml:"groupId"`
+	ArtifactID           string       `xml:"artifactId"`
+	Version              string       `xml:"version"`
+	Packaging            string       `xml:"packaging"`
+	Parent               *Parent      `xml:"parent"`
+	Modules              []string     `xml:"modules>module"`
+	Properties           Properties   `xml:"properties"`
+	DependencyManagement []Dependency `xml:"dependencyManagement>dependencies>dependency"`
+	Dependencies         []Dependency `xml:"dependencies>dependency"`
+	Repositories         []Repository `xml:"repositories>repository"`
+	Build                Build        `xml:"build"`
+}
+
+// ManagementKey identifies a dependency the way dependencyManagement
+// does: by coordinates, type and classifier but not version.
+func (d Dependency) ManagementKey() string {
+	depType := d.Type
+	if depType == "" {
+		depType = "jar"
+	}
+	return fmt.Sprintf("%s:%s:%s:%s", d.GroupID, d.ArtifactID, depType, d.Classifier)
+}
+
+func (d Dependency) IsOptional() bool {
+	return d.Optional == "true"
+}
+
+func (d Dependency) String() string {
+	return fmt.Sprintf("%s:%s:%s", d.GroupID, d.ArtifactID, d.Version)
+}
+
+func (p *Properties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	props := Properties{}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var value string
+			if err := d.DecodeElement(&value, &t); err != nil {
+				return err
+			}
+			props[t.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			*p = props
+			return nil
+		}
+	}
+}
+
+// Parse reads a raw pom.xml without applying inheritance or interpolation.
+func Parse(r io.Reader) (*Project, error) {
+	var project Project
+	if err := xml.NewDecoder(r).Decode(&project); err != nil {
+		return nil, err
+	}
+
+	project.trim()
+	if project.Properties == nil {
+		project.Properties = Properties{}
+	}
+	return &project, nil
+}
+
+func (p *Project) trim() {
+	p.GroupID = strings.TrimSpace(p.GroupID)
+	p.ArtifactID = strings.TrimSpace(p.ArtifactID)
+	p.Version = strings.TrimSpace(p.Version)
+	p.Packaging = strings.TrimSpace(p.Packaging)
+	if p.Parent != nil {
+		p.Parent.GroupID = strings.TrimSpace(p.Parent.GroupID)
+		p.Parent.ArtifactID = strings.TrimSpace(p.Parent.ArtifactID)
+		p.Parent.Version = strings.TrimSpace(p.Parent.Version)
+	}
+	for i := range p.DependencyManagement {
+		p.DependencyManagement[i].trim()
+	}
+	for i := range p.Dependencies {
+		p.Dependencies[i].trim()
+	}
+}
+
+func (d *Dependency) trim() {
+	d.GroupID = strings.TrimSpace(d.GroupID)
+	d.ArtifactID = strings.TrimSpace(d.ArtifactID)
+	d.Version = strings.TrimSpace(d.Version)
+	d.Type = strings.TrimSpace(d.Type)
+	d.Classifier = strings.TrimSpace(d.Classifier)
+	d.Scope = strings.TrimSpace(d.Scope)
+	d.Optional = strings.TrimSpace(d.Optional)
+	for i := range d.Exclusions {
+		d.Exclusions[i].GroupID = strings.TrimSpace(d.Exclusions[i].GroupID)
+		d.Exclusions[i].ArtifactID = strings.TrimSpace(d.Exclusions[i].ArtifactID)
+	}
+}
@@ -0,0 +1,173 @@
+package pom
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Well known qualifiers in ascending order. A version without a qualifier
+// is a release and sorts between "snapshot" and "sp".
+var qualifierOrder = map[string]int{
+	"alpha":     1,
+	"a":         1,
+	"beta":      2,
+	"b":         2,
+	"milestone": 3,
+	"m":         3,
+	"rc":        4,
+	"cr":        4,
+	"snapshot":  5,
+	"":          6,
+	"ga":        6,
+	"final":     6,
+	"release":   6,
+	"sp":        7,
+}
+
+type versionItem struct {
+	value    string
+	isNumber bool
+}
+
+func splitVersion(version string) []versionItem {
+	var items []versionItem
+	var current strings.Builder
+	currentIsNumber := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			items = append(items, versionItem{value: current.String(), isNumber: currentIsNumber})
+			current.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(strings.TrimSpace(version)) {
+		if r == '.' || r == '-' || r == '_' || r == '+' {
+			flush()
+			continue
+		}
+		isDigit := unicode.IsDigit(r)
+		if current.Len() > 0 && isDigit != currentIsNumber {
+			flush()
+		}
+		currentIsNumber = isDigit
+		current.WriteRune(r)
+	}
+	flush()
+	return items
+}
+
+func compareNumbers(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareQualifiers(a, b string) int {
+	rankA, knownA := qualifierOrder[a]
+	rankB, knownB := qualifierOrder[b]
+	switch {
+	case knownA && knownB:
+		return rankA - rankB
+	case knownA:
+		return -1
+	case knownB:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func compareItems(a, b versionItem) int {
+	switch {
+	case a.isNumber && b.isNumber:
+		return compareNumbers(a.value, b.value)
+	case a.isNumber:
+		return 1
+	case b.isNumber:
+		return -1
+	default:
+		return compareQualifiers(a.value, b.value)
+	}
+}
+
+// CompareVersions orders two Maven versions, returning a negative number
+// when a is older than b, zero when they are equal and a positive number
+// otherwise. Numeric parts are compared numerically and qualifiers follow
+// Maven's alpha < beta < milestone < rc < snapshot < release < sp order.
+func CompareVersions(a, b string) int {
+	itemsA := splitVersion(a)
+	itemsB := splitVersion(b)
+
+	for i := 0; i < len(itemsA) || i < len(itemsB); i++ {
+		var itemA, itemB versionItem
+		if i < len(itemsA) {
+			itemA = itemsA[i]
+		}
+		if i < len(itemsB) {
+			itemB = itemsB[i]
+		}
+
+		// A missing item counts as 0 against a number and as a release
+		// against a qualifier, so 1.0 == 1.0.0 and 1.0-rc1 < 1.0.
+		if i >= len(itemsA) {
+			itemA = versionItem{isNumber: itemB.isNumber}
+			if itemB.isNumber {
+				itemA.value = "0"
+			}
+		}
+		if i >= len(itemsB) {
+			itemB = versionItem{isNumber: itemA.isNumber}
+			if itemA.isNumber {
+				itemB.value = "0"
+			}
+		}
+
+		if c := compareItems(itemA, itemB); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// IsStable reports whether the version is a release rather than an alpha,
+// beta, milestone, release candidate or snapshot.
+func IsStable(version string) bool {
+	for _, item := range splitVersion(version) {
+		if item.isNumber {
+			continue
+		}
+		if rank, ok := qualifierOrder[item.value]; ok && rank < qualifierOrder[""] {
+			return false
+		}
+	}
+	return true
+}
+
+// VersionPrefix returns the first n numeric components of the version,
+// e.g. "1.2" for 1.2.3-jre with n = 2. It reports false when the version
+// does not start with n numbers.
+func VersionPrefix(version string, n int) (string, bool) {
+	items := splitVersion(version)
+	if len(items) < n {
+		return "", false
+	}
+
+	parts := make([]string, n)
+	for i := 0; i < n; i++ {
+		if !items[i].isNumber {
+			return "", false
+		}
+		parts[i] = strings.TrimLeft(items[i].value, "0")
+		if parts[i] == "" {
+			parts[i] = "0"
+		}
+	}
+	return strings.Join(parts, "."), true
+}
@@ -0,0 +1,85 @@
+package pom
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.0.0", 0},
+		{"1", "1.0.0", 0},
+		{"1.0-ga", "1.0", 0},
+		{"1.0-final", "1.0", 0},
+		{"1.0.RELEASE", "1.0", 0},
+		{"1.0-alpha-1", "1.0-a1", 0},
+		{"1.2", "1.10", -1},
+		{"1.9.9", "1.10", -1},
+		{"2.0", "10.0", -1},
+		{"1.0.01", "1.0.1", 0},
+		{"1.0-alpha", "1.0-beta", -1},
+		{"1.0-beta", "1.0-milestone", -1},
+		{"1.0-milestone", "1.0-rc", -1},
+		{"1.0-rc", "1.0-cr", 0},
+		{"1.0-rc1", "1.0-rc2", -1},
+		{"1.0-rc", "1.0-SNAPSHOT", -1},
+		{"1.0-SNAPSHOT", "1.0", -1},
+		{"1.0", "1.0-sp", -1},
+		{"1.0-sp", "1.0.1", -1},
+		{"1.0-alpha", "1.0-sp", -1},
+		{"1.0-rc1", "0.9", 1},
+		{"1.0-foo", "1.0-alpha", 1},
+		{"1.0-bar", "1.0-foo", -1},
+		{"32.1.2-jre", "32.1.2-android", 1},
+	}
+	for _, tt := range tests {
+		got := CompareVersions(tt.a, tt.b)
+		if sign(got) != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if reverse := CompareVersions(tt.b, tt.a); sign(reverse) != -tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.b, tt.a, reverse, -tt.want)
+		}
+	}
+}
+
+func TestQualifierOrder(t *testing.T) {
+	ordered := []string{"1.0-alpha", "1.0-beta", "1.0-rc", "1.0-SNAPSHOT", "1.0", "1.0-sp"}
+	for i := 1; i < len(ordered); i++ {
+		if CompareVersions(ordered[i-1], ordered[i]) >= 0 {
+			t.Errorf("%s is not older than %s", ordered[i-1], ordered[i])
+		}
+	}
+}
+
+func TestIsStable(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"1.0", true},
+		{"1.0.Final", true},
+		{"1.0-sp1", true},
+		{"32.1.2-jre", true},
+		{"1.0-alpha1", false},
+		{"1.0-M2", false},
+		{"1.0-RC1", false},
+		{"1.0-SNAPSHOT", false},
+	}
+	for _, tt := range tests {
+		if got := IsStable(tt.version); got != tt.want {
+			t.Errorf("IsStable(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -1,17 +1,13 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
+	"jpkg/pkg/lock"
 	"os"
 	"os/exec"
 )
 
-type DependencyLock struct {
-	Dependencies map[string]string `json:"dependencies"`
-}
-
 func main() {
 	flag.Parse()
 	args := flag.Args()
@@ -42,23 +38,17 @@ func main() {
 
 // GetJarFileName retrieves the JAR file name for a given dependency
 func getJarFileName(name string) (string, error) {
-	lockFile := "dependencies-lock.json"
-	var lock DependencyLock
-
-	file, err := os.Open(lockFile)
+	l, err := lock.Read()
 	if err != nil {
 		return "", err
 	}
-	defer file.Close()
 
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&lock); err != nil {
-		return "", err
-	}
-
-	jarFileName, exists := lock.Dependencies[name]
-	if !exists {
+	artifacts := l.Find(name)
+	if len(artifacts) == 0 {
 		return "", fmt.Errorf("dependency %s not found in lock file", name)
 	}
-	return jarFileName, nil
+	if len(artifacts) > 1 {
+		return "", fmt.Errorf("dependency %s is ambiguous, use groupId:artifactId", name)
+	}
+	return artifacts[0].File, nil
 }